@@ -2,7 +2,9 @@ package gpg
 
 import (
 	"context"
+	"net/http"
 
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -19,30 +21,76 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 // Backend returns an instance of the backend for the GPG plugin
 func Backend() *backend {
 	var b backend
+	b.httpClient = cleanhttp.DefaultClient()
+	b.rateLimiter = newKeyRateLimiter()
+	b.usageTracker = newKeyUsageTracker()
 	b.Backend = &framework.Backend{
 		Help: backendHelp,
 		Paths: []*framework.Path{
-			pathKeys(&b),
+			pathConfig(&b),
 			pathListKeys(&b),
+			pathKeysByFingerprint(&b),
+			pathKeyHealth(&b),
+			pathWKD(&b),
 			pathExportKeys(&b),
+			pathExportSubkey(&b),
+			pathBackup(&b),
+			pathRestore(&b),
+			pathRotate(&b),
+			pathSubkey(&b),
+			pathAddEncryptionSubkey(&b),
+			pathTags(&b),
+			pathIdentities(&b),
+			pathKeyDisable(&b),
+			pathKeyEnable(&b),
+			pathKeyRateLimit(&b),
+			// The key names can contain "/" so pathKeys must come after the other paths under keys/.
+			pathKeys(&b),
 			pathSign(&b),
+			pathSignDigest(&b),
+			pathSignGit(&b),
 			pathVerify(&b),
+			pathVerifyKeys(&b),
+			pathCertify(&b),
+			pathRevoke(&b),
+			pathEncrypt(&b),
 			pathDecrypt(&b),
+			pathRewrap(&b),
+			pathSeal(&b),
 			pathShowSessionKey(&b),
+			pathInspect(&b),
 		},
 		PathsSpecial: &logical.Paths{
 			SealWrapStorage: []string{
 				"key/",
 			},
 		},
-		Secrets:     []*framework.Secret{},
-		BackendType: logical.TypeLogical,
+		Secrets:      []*framework.Secret{},
+		BackendType:  logical.TypeLogical,
+		PeriodicFunc: b.periodicFunc,
 	}
 	return &b
 }
 
 type backend struct {
 	*framework.Backend
+
+	// httpClient is used to fetch keys from keyservers
+	httpClient *http.Client
+
+	// rateLimiter enforces the maximum number of operations per second of the keys
+	rateLimiter *keyRateLimiter
+
+	// pkcs11 gives access to the private keys stored in PKCS#11 tokens, nil if none is available
+	pkcs11 PKCS11Provider
+
+	// usageTracker counts the operations made with the keys until they are periodically written to the storage
+	usageTracker *keyUsageTracker
+}
+
+// periodicFunc writes the usage of the keys counted since it last ran.
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	return b.usageTracker.flush(ctx, req.Storage)
 }
 
 const backendHelp = `
@@ -3,8 +3,8 @@ package gpg
 import (
 	"context"
 	"encoding/hex"
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/hashicorp/vault/sdk/logical"
-	"golang.org/x/crypto/openpgp"
 	"reflect"
 	"strings"
 	"testing"
@@ -27,6 +27,7 @@ func TestBackend_CRUD(t *testing.T) {
 	testAccStepReadKey(t, b, storage, "test", keyData)
 	testAccStepDeleteKey(t, b, storage, "test")
 	testAccStepListKey(t, b, storage, []string{"test2", "test3"})
+	testAccStepListKeyDetailed(t, b, storage, []string{"test2", "test3"})
 	testAccStepReadKey(t, b, storage, "test", nil)
 }
 
@@ -85,6 +86,30 @@ func TestBackend_InvalidCharIdentity(t *testing.T) {
 		},
 		true,
 	)
+	testAccStepCreateKey(
+		t,
+		b,
+		storage,
+		"test",
+		map[string]interface{}{
+			"real_name": "Vault\x00",
+			"email":     "vault@example.com",
+		},
+		true,
+	)
+	for _, email := range []string{"vault", "vault@", "@example.com", "vault@@example.com", "vault @example.com"} {
+		testAccStepCreateKey(
+			t,
+			b,
+			storage,
+			"test",
+			map[string]interface{}{
+				"real_name": "Vault",
+				"email":     email,
+			},
+			true,
+		)
+	}
 }
 
 func testAccStepCreateKey(t *testing.T, b logical.Backend, s logical.Storage, name string, keyData map[string]interface{}, expectFail bool) {
@@ -101,6 +126,8 @@ func testAccStepCreateKey(t *testing.T, b logical.Backend, s logical.Storage, na
 		if resp.IsError() {
 			t.Error(resp.Error())
 		}
+	} else if err == nil && !resp.IsError() {
+		t.Errorf("expected to fail, key data: %#v", keyData)
 	}
 }
 
@@ -112,6 +139,12 @@ func testAccStepReadKey(t *testing.T, b logical.Backend, storage logical.Storage
 		Storage:   storage,
 	})
 
+	if keyData == nil {
+		if !response.IsError() || response.Error().Error() != "key not found: "+name {
+			t.Errorf("expected a key not found error, got %#v", response)
+		}
+		return
+	}
 	if err != nil {
 		t.Error(err)
 	}
@@ -120,14 +153,16 @@ func testAccStepReadKey(t *testing.T, b logical.Backend, storage logical.Storage
 	}
 
 	if response == nil {
-		if keyData == nil {
-			return
-		}
 		t.Errorf("response not expected: %#v", response)
 		return
 	}
 
-	r := strings.NewReader(response.Data["public_key"].(string))
+	publicKey := response.Data["public_key"].(string)
+	if !strings.Contains(publicKey, "\nComment: Vault key "+name+"\n") {
+		t.Errorf("the armored public key does not carry the key name: %s", publicKey)
+	}
+
+	r := strings.NewReader(publicKey)
 	el, err := openpgp.ReadArmoredKeyRing(r)
 	if err != nil {
 		t.Fatal(err)
@@ -194,6 +229,54 @@ func testAccStepListKey(t *testing.T, b logical.Backend, storage logical.Storage
 	}
 }
 
+func testAccStepListKeyDetailed(t *testing.T, b logical.Backend, storage logical.Storage, names []string) {
+	response, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "keys/",
+		Data: map[string]interface{}{
+			"detailed": true,
+		},
+		Storage: storage,
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+	if response.IsError() {
+		t.Error(response.Error())
+	}
+
+	keyInfo := response.Data["key_info"].(map[string]interface{})
+	if len(keyInfo) != len(names) {
+		t.Errorf("expected %d keys, got %#v", len(names), keyInfo)
+	}
+	for _, name := range names {
+		info, ok := keyInfo[name].(map[string]interface{})
+		if !ok {
+			t.Errorf("no information found for key %s: %#v", name, keyInfo)
+			continue
+		}
+		if !reflect.DeepEqual(info["fingerprint"], testAccReadFingerprint(t, b, storage, name)) {
+			t.Errorf("fingerprint of key %s does not match: %#v", name, info)
+		}
+		if info["algorithm"] != "rsa" {
+			t.Errorf("algorithm of key %s does not match: %#v", name, info)
+		}
+	}
+}
+
+func testAccReadFingerprint(t *testing.T, b logical.Backend, storage logical.Storage, name string) interface{} {
+	response, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "keys/" + name,
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.Data["fingerprint"]
+}
+
 func getTestBackend(t *testing.T) (logical.Backend, logical.Storage) {
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
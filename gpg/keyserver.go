@@ -0,0 +1,65 @@
+package gpg
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxKeyserverResponseSize limits the size of the public keys fetched from a keyserver.
+const maxKeyserverResponseSize = 1 << 20
+
+// fetchPublicKey retrieves from a HKPS keyserver the public key matching the fingerprint.
+func (b *backend) fetchPublicKey(ctx context.Context, keyserverURL string, fingerprint string) (*openpgp.Entity, error) {
+	fingerprint = strings.ToLower(strings.Replace(fingerprint, " ", "", -1))
+	if _, err := hex.DecodeString(fingerprint); err != nil || fingerprint == "" {
+		return nil, fmt.Errorf("invalid fingerprint %s; must be hex encoded", fingerprint)
+	}
+
+	u, err := url.Parse(keyserverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyserver_url: %s", err)
+	}
+	switch u.Scheme {
+	case "https":
+	case "hkps":
+		u.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("unsupported keyserver_url scheme %s; must be \"hkps\" or \"https\"", u.Scheme)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/pks/lookup"
+	u.RawQuery = url.Values{
+		"op":      {"get"},
+		"options": {"mr"},
+		"search":  {"0x" + fingerprint},
+	}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the key from the keyserver: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch the key from the keyserver: %s", resp.Status)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(io.LimitReader(resp.Body, maxKeyserverResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the key returned by the keyserver: %s", err)
+	}
+	for _, entity := range el {
+		if hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]) == fingerprint {
+			return entity, nil
+		}
+	}
+	return nil, fmt.Errorf("the fingerprint of the key returned by the keyserver does not match %s", fingerprint)
+}
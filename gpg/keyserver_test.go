@@ -0,0 +1,123 @@
+package gpg
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/vault/sdk/logical"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGPG_ImportKeyFromKeyserver(t *testing.T) {
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gpgPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := hex.EncodeToString(el[0].PrimaryKey.Fingerprint[:])
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pks/lookup" || r.URL.Query().Get("op") != "get" {
+			http.NotFound(w, r)
+			return
+		}
+		if !strings.EqualFold(r.URL.Query().Get("search"), "0x"+fingerprint) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(gpgPublicKey))
+	}))
+	defer server.Close()
+
+	storage := &logical.InmemStorage{}
+	b := Backend()
+	b.httpClient = server.Client()
+
+	importKey := func(keyserverURL, fingerprint string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/test",
+			Data: map[string]interface{}{
+				"generate":      false,
+				"keyserver_url": keyserverURL,
+				"fingerprint":   fingerprint,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := importKey(server.URL, fingerprint); !resp.IsError() {
+		t.Fatal("expected to fail, importing keys from a keyserver is disabled by default")
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data: map[string]interface{}{
+			"allow_keyserver_import": true,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := importKey(strings.Replace(server.URL, "https://", "http://", 1), fingerprint); !resp.IsError() {
+		t.Fatal("expected to fail, the keyserver must be reached over HKPS")
+	}
+	if resp := importKey(server.URL, "0000000000000000000000000000000000000000"); !resp.IsError() {
+		t.Fatal("expected to fail, no key matches the fingerprint")
+	}
+	if resp := importKey(server.URL, strings.ToUpper(fingerprint)); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	reqRead := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["fingerprint"] != fingerprint {
+		t.Fatalf("expected fingerprint %s, got %s", fingerprint, resp.Data["fingerprint"])
+	}
+
+	reqEncrypt := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt/test",
+		Data: map[string]interface{}{
+			"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqEncrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	reqSign := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Data: map[string]interface{}{
+			"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+	}
+	resp, _ = b.HandleRequest(context.Background(), reqSign)
+	if !resp.IsError() {
+		t.Fatal("expected to fail, the key does not have a private key")
+	}
+}
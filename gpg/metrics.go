@@ -0,0 +1,36 @@
+package gpg
+
+import (
+	"context"
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"time"
+)
+
+// withMetrics wraps an operation made with a named key to count its successes and failures
+// and to measure its latency.
+func withMetrics(operation string, callback framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		start := time.Now()
+		resp, err := callback(ctx, req, data)
+
+		// The operations made with several candidate keys do not have a name field and are recorded without a key
+		key := ""
+		if _, ok := data.Schema["name"]; ok {
+			key = data.Get("name").(string)
+		}
+		labels := []metrics.Label{
+			{Name: "key", Value: key},
+			{Name: "operation", Value: operation},
+		}
+		status := "success"
+		if err != nil || resp.IsError() {
+			status = "failure"
+		}
+		metrics.IncrCounterWithLabels([]string{"gpg", "operation", status}, 1, labels)
+		metrics.MeasureSinceWithLabels([]string{"gpg", "operation", "duration"}, start, labels)
+
+		return resp, err
+	}
+}
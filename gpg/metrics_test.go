@@ -0,0 +1,81 @@
+package gpg
+
+import (
+	"context"
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/logical"
+	"testing"
+	"time"
+)
+
+func TestGPG_Metrics(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Hour, time.Hour)
+	config := metrics.DefaultConfig("")
+	config.EnableHostname = false
+	config.EnableRuntimeMetrics = false
+	if _, err := metrics.NewGlobal(config, sink); err != nil {
+		t.Fatal(err)
+	}
+	defer metrics.NewGlobal(metrics.DefaultConfig(""), &metrics.BlackholeSink{})
+
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(input string) {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/test",
+			Data: map[string]interface{}{
+				"input": input,
+			},
+		}
+		b.HandleRequest(context.Background(), req)
+	}
+	sign("dGhlIHF1aWNrIGJyb3duIGZveA==")
+	sign("dGhlIHF1aWNrIGJyb3duIGZveA==")
+	sign("Not base64")
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "verify",
+		Data: map[string]interface{}{
+			"names":     "test",
+			"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"signature": "Not a signature",
+		},
+	}
+	b.HandleRequest(context.Background(), req)
+
+	data := sink.Data()
+	if len(data) == 0 {
+		t.Fatal("no metrics have been recorded")
+	}
+	counters := data[0].Counters
+	if count := counters["gpg.operation.success;key=test;operation=sign"].Count; count != 2 {
+		t.Fatalf("expected 2 successful sign operations, got %d", count)
+	}
+	if count := counters["gpg.operation.failure;key=test;operation=sign"].Count; count != 1 {
+		t.Fatalf("expected 1 failed sign operation, got %d", count)
+	}
+	if count := data[0].Samples["gpg.operation.duration;key=test;operation=sign"].Count; count != 3 {
+		t.Fatalf("expected 3 latency samples, got %d", count)
+	}
+	if count := counters["gpg.operation.failure;key=;operation=verify-multi"].Count; count != 1 {
+		t.Fatalf("expected 1 failed verification with the candidate keys, got %d", count)
+	}
+}
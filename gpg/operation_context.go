@@ -0,0 +1,38 @@
+package gpg
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// maxOperationContextLength is the maximum length of the context of an operation, it is kept short as it is
+// written to the audit log with every request and response.
+const maxOperationContextLength = 512
+
+// operationContextField returns the schema of the context field of the cryptographic operations.
+func operationContextField() *framework.FieldSchema {
+	return &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "An opaque string, for example a transaction ID, returned as is in the response to correlate the operation in the audit log.",
+	}
+}
+
+// withOperationContext wraps an operation to return the context given in the request in its response.
+func withOperationContext(callback framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		operationContext := data.Get("context").(string)
+		if len(operationContext) > maxOperationContextLength {
+			return logical.ErrorResponse(fmt.Sprintf("context must be at most %d bytes", maxOperationContextLength)), logical.ErrInvalidRequest
+		}
+		resp, err := callback(ctx, req, data)
+		if operationContext != "" && resp != nil && !resp.IsError() {
+			if resp.Data == nil {
+				resp.Data = map[string]interface{}{}
+			}
+			resp.Data["context"] = operationContext
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,52 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"strings"
+	"testing"
+)
+
+func TestGPG_OperationContext(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle("keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+
+	if resp := handle("sign/test", map[string]interface{}{"input": input, "context": strings.Repeat("a", maxOperationContextLength+1)}); !resp.IsError() {
+		t.Fatal("expected to fail, the context is too long")
+	}
+	if resp := handle("sign/test", map[string]interface{}{"input": input}); resp.IsError() || resp.Data["context"] != nil {
+		t.Fatalf("expected no context in the response, got %#v", resp)
+	}
+	if resp := handle("sign/test", map[string]interface{}{"input": input, "context": "release-1.2.3"}); resp.IsError() || resp.Data["context"] != "release-1.2.3" {
+		t.Fatalf("expected the context to be returned, got %#v", resp)
+	}
+
+	resp := handle("encrypt/test", map[string]interface{}{"plaintext": input, "format": "base64", "context": "tx-42"})
+	if resp.IsError() || resp.Data["context"] != "tx-42" {
+		t.Fatalf("expected the context to be returned, got %#v", resp)
+	}
+	resp = handle("decrypt/test", map[string]interface{}{"ciphertext": resp.Data["ciphertext"], "context": "tx-43"})
+	if resp.IsError() || resp.Data["context"] != "tx-43" {
+		t.Fatalf("expected the context to be returned, got %#v", resp)
+	}
+	if resp = handle("decrypt/test", map[string]interface{}{"ciphertext": "Not base64 encoded", "context": "tx-44"}); !resp.IsError() || resp.Data["context"] != nil {
+		t.Fatalf("expected an error response without the context, got %#v", resp)
+	}
+}
@@ -0,0 +1,222 @@
+package gpg
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathBackup(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "backup/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"backup_key": {
+				Type:        framework.TypeString,
+				Description: "A secret authenticating the backup. The same backup_key must be given to restore it.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathBackupRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathBackupRead,
+			},
+		},
+		HelpSynopsis:    pathBackupHelpSyn,
+		HelpDescription: pathBackupHelpDesc,
+	}
+}
+
+func pathRestore(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "restore/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"backup": {
+				Type:        framework.TypeString,
+				Description: "The backup of the key as returned by the backup endpoint.",
+			},
+			"backup_key": {
+				Type:        framework.TypeString,
+				Description: "The secret the backup has been authenticated with. Required if the backup was made with a backup_key.",
+			},
+			"force": {
+				Type:        framework.TypeBool,
+				Description: "Allows to overwrite an existing key. The previous key material is lost.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRestoreWrite,
+			},
+		},
+		HelpSynopsis:    pathRestoreHelpSyn,
+		HelpDescription: pathRestoreHelpDesc,
+	}
+}
+
+// keyBackup is a backed up key entry with its HMAC when it is authenticated with a backup_key, so a modified
+// backup is not restored.
+type keyBackup struct {
+	Key  json.RawMessage `json:"key"`
+	HMAC []byte          `json:"hmac,omitempty"`
+}
+
+func (b *backend) pathBackupRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	// The backup holds the private key, it must not give access to keys that cannot be exported.
+	if !entry.Exportable {
+		return logical.ErrorResponse("key is not exportable"), logical.ErrInvalidRequest
+	}
+
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	signed := keyBackup{Key: encoded}
+	if backupKey := data.Get("backup_key").(string); backupKey != "" {
+		signed.HMAC = backupHMAC(backupKey, encoded)
+	}
+	backup, err := json.Marshal(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The backup holds the private key, it is response-wrapped like an export.
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"backup": base64.StdEncoding.EncodeToString(backup),
+		},
+	}
+	if req.WrapInfo == nil || req.WrapInfo.TTL == 0 {
+		resp.WrapInfo = &wrapping.ResponseWrapInfo{
+			TTL: exportWrapTTL,
+		}
+	}
+	return resp, nil
+}
+
+// backupHMAC returns the HMAC of a backed up key entry computed with the backup key.
+func backupHMAC(backupKey string, encoded []byte) []byte {
+	h := hmac.New(sha256.New, []byte(backupKey))
+	h.Write(encoded)
+	return h.Sum(nil)
+}
+
+func (b *backend) pathRestoreWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	backup := data.Get("backup").(string)
+	if backup == "" {
+		return logical.ErrorResponse("backup must be provided"), logical.ErrInvalidRequest
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(backup)
+	if err != nil {
+		return logical.ErrorResponse("unable to decode backup as base64"), logical.ErrInvalidRequest
+	}
+	var signed keyBackup
+	if err = json.Unmarshal(decoded, &signed); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to read backup: %s", err)), logical.ErrInvalidRequest
+	}
+	// The backup can be restored in any mount, the backup key is shared by the operators instead of being bound to
+	// the mount that made it.
+	backupKey := data.Get("backup_key").(string)
+	switch {
+	case len(signed.HMAC) > 0 && backupKey == "":
+		return logical.ErrorResponse("the backup is authenticated, backup_key must be provided"), logical.ErrInvalidRequest
+	case len(signed.HMAC) == 0 && backupKey != "":
+		return logical.ErrorResponse("the backup was not made with a backup_key"), logical.ErrInvalidRequest
+	case backupKey != "" && !hmac.Equal(backupHMAC(backupKey, signed.Key), signed.HMAC):
+		return logical.ErrorResponse("the backup has been modified or was made with another backup_key"), logical.ErrInvalidRequest
+	}
+	entry := keyEntry{Enabled: true}
+	if err = json.Unmarshal(signed.Key, &entry); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to read backup: %s", err)), logical.ErrInvalidRequest
+	}
+	// Only the exportable keys stored by Vault can be backed up.
+	if !entry.Exportable || entry.KeySource != "" {
+		return logical.ErrorResponse("the backup must be of an exportable key stored by Vault"), logical.ErrInvalidRequest
+	}
+
+	existing, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if !data.Get("force").(bool) {
+			return logical.ErrorResponse(fmt.Sprintf("key %s already exists; set force to true to overwrite it", name)), logical.ErrInvalidRequest
+		}
+		if !existing.Exportable && entry.Exportable {
+			return logical.ErrorResponse(fmt.Sprintf("key %s is not exportable and cannot be made exportable", name)), logical.ErrInvalidRequest
+		}
+	}
+	keyring, err := b.keyring(&entry)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("the backup does not contain a valid key: %s", err)), logical.ErrInvalidRequest
+	}
+	// The metadata are derived from the key material so they cannot disagree with it.
+	if err = entry.setMetadata(keyring[0]); err != nil {
+		return nil, err
+	}
+
+	policy, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if err = policy.check(entry.Algorithm, entry.KeyBits); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err = policy.checkName(name); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathBackupHelpSyn = "Back up a named GPG key"
+
+const pathBackupHelpDesc = `
+This path returns a base64-encoded backup of the named exportable GPG key,
+including its private key, its previous versions and all its stored
+attributes. It can be restored with the restore endpoint of any mount. When a
+backup_key is given, the backup is authenticated with it and the same
+backup_key is required to restore it. The response is always wrapped, the
+requested wrapping TTL is used if any.
+`
+
+const pathRestoreHelpSyn = "Restore a named GPG key from a backup"
+
+const pathRestoreHelpDesc = `
+This path restores a GPG key from a backup exactly as it was backed up, in
+this mount or in another one. A backup made with a backup_key is only
+restored with the same backup_key, and rejected if it has been modified. An
+existing key is only overwritten if force is true.
+`
@@ -0,0 +1,170 @@
+package gpg
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGPG_BackupRestore(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name":  "Vault GPG test",
+			"exportable": true,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "rotate/test",
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "backup/test",
+		Data: map[string]interface{}{
+			"backup_key": "shared secret",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != exportWrapTTL {
+		t.Fatalf("expected the backup to be wrapped with a TTL of %s, got %#v", exportWrapTTL, resp.WrapInfo)
+	}
+	backup := resp.Data["backup"].(string)
+
+	restore := func(name string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "restore/" + name,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	if resp = restore("test", map[string]interface{}{"backup": backup, "backup_key": "shared secret"}); !resp.IsError() {
+		t.Fatal("expected to fail, the key already exists")
+	}
+	for _, name := range []string{"team/health", "team/identities", "team/disable", "by-fingerprint/test"} {
+		if resp = restore(name, map[string]interface{}{"backup": backup, "backup_key": "shared secret"}); !resp.IsError() {
+			t.Fatalf("expected to fail, the key name %s collides with an endpoint under keys/", name)
+		}
+	}
+	if resp = restore("restored", map[string]interface{}{"backup": "Not base64"}); !resp.IsError() {
+		t.Fatal("expected to fail, the backup is not base64")
+	}
+	if resp = restore("restored", map[string]interface{}{"backup": "eyJTZXJpYWxpemVkS2V5IjoiIn0="}); !resp.IsError() {
+		t.Fatal("expected to fail, the backup does not contain a key")
+	}
+	var signed keyBackup
+	decoded, _ := base64.StdEncoding.DecodeString(backup)
+	if err = json.Unmarshal(decoded, &signed); err != nil {
+		t.Fatal(err)
+	}
+	signed.Key = json.RawMessage(strings.Replace(string(signed.Key), `"KeySource":""`, `"KeySource":"pkcs11"`, 1))
+	tampered, _ := json.Marshal(signed)
+	if resp = restore("restored", map[string]interface{}{"backup": base64.StdEncoding.EncodeToString(tampered), "backup_key": "shared secret"}); !resp.IsError() {
+		t.Fatal("expected to fail, the backup has been modified")
+	}
+	if resp = restore("restored", map[string]interface{}{"backup": backup}); !resp.IsError() {
+		t.Fatal("expected to fail, the backup_key is missing")
+	}
+	if resp = restore("restored", map[string]interface{}{"backup": backup, "backup_key": "another secret"}); !resp.IsError() {
+		t.Fatal("expected to fail, the backup was made with another backup_key")
+	}
+
+	// The backup can be restored in another mount, of another cluster for example
+	otherStorage := &logical.InmemStorage{}
+	otherMount := Backend()
+	if resp, _ := otherMount.HandleRequest(context.Background(), &logical.Request{
+		Storage:   otherStorage,
+		Operation: logical.UpdateOperation,
+		Path:      "restore/restored",
+		Data:      map[string]interface{}{"backup": backup, "backup_key": "shared secret"},
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp = restore("restored", map[string]interface{}{"backup": backup, "backup_key": "shared secret"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	original, err := b.key(context.Background(), storage, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := b.key(context.Background(), storage, "restored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(original, restored) {
+		t.Fatalf("expected the restored key %#v to match the original key %#v", restored, original)
+	}
+	restored, err = otherMount.key(context.Background(), otherStorage, "restored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(original, restored) {
+		t.Fatalf("expected the key restored in the other mount %#v to match the original key %#v", restored, original)
+	}
+
+	// A backup made without a backup_key is not authenticated
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "backup/test",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unauthenticated := resp.Data["backup"].(string)
+	if resp = restore("unauthenticated", map[string]interface{}{"backup": unauthenticated, "backup_key": "shared secret"}); !resp.IsError() {
+		t.Fatal("expected to fail, the backup was not made with a backup_key")
+	}
+	if resp = restore("test", map[string]interface{}{"backup": unauthenticated, "force": true}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/protected",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "backup/protected",
+	}
+	if resp, _ = b.HandleRequest(context.Background(), req); !resp.IsError() {
+		t.Fatal("expected to fail, a non-exportable key cannot be backed up")
+	}
+	if resp = restore("protected", map[string]interface{}{"backup": unauthenticated, "force": true}); !resp.IsError() {
+		t.Fatal("expected to fail, a non-exportable key cannot be replaced by an exportable one")
+	}
+}
@@ -0,0 +1,111 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"strings"
+)
+
+func pathCertify(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certify/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+			"public_key": {
+				Type:        framework.TypeString,
+				Description: "The ASCII-armored GPG public key to certify",
+			},
+			"identity": {
+				Type:        framework.TypeString,
+				Description: `The identity of the public key to certify, e.g. "John Doe <john.doe@example.com>". If empty, all the identities are certified.`,
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathCertifyWrite,
+			},
+		},
+		HelpSynopsis:    pathCertifyHelpSyn,
+		HelpDescription: pathCertifyHelpDesc,
+	}
+}
+
+func (b *backend) pathCertifyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	if err = entry.checkUsable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	signer, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err = decryptPrivateKeys(signer, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(data.Get("public_key").(string)))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	entity := el[0]
+
+	identities := []string{data.Get("identity").(string)}
+	if identities[0] == "" {
+		identities = identities[:0]
+		for identity := range entity.Identities {
+			identities = append(identities, identity)
+		}
+	}
+	for _, identity := range identities {
+		if _, ok := entity.Identities[identity]; !ok {
+			return logical.ErrorResponse(fmt.Sprintf("identity %s not found in the public key", identity)), logical.ErrInvalidRequest
+		}
+		if err = entity.SignIdentity(identity, signer, nil); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = entity.Serialize(w); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": buf.String(),
+		},
+	}, nil
+}
+
+const pathCertifyHelpSyn = "Certify the identities of a GPG public key using a named GPG key"
+
+const pathCertifyHelpDesc = `
+This path uses the named GPG key from the request path to certify the
+identities of a user provided GPG public key. The certified public key is
+returned ASCII-armored.
+`
@@ -0,0 +1,97 @@
+package gpg
+
+import (
+	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/vault/sdk/logical"
+	"strings"
+	"testing"
+)
+
+func TestGPG_Certify(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqRead := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := el[0]
+
+	identity := "Vault (Comment) <vault@example.com>"
+	reqCertify := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "certify/test",
+		Data: map[string]interface{}{
+			"public_key": gpgPublicKey,
+			"identity":   identity,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqCertify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	el, err = openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certified := false
+	for _, sig := range el[0].Identities[identity].Signatures {
+		if sig.IssuerKeyId == nil || *sig.IssuerKeyId != signer.PrimaryKey.KeyId {
+			continue
+		}
+		if err = signer.PrimaryKey.VerifyUserIdSignature(identity, el[0].PrimaryKey, sig); err != nil {
+			t.Fatalf("certification is not valid: %s", err)
+		}
+		certified = true
+	}
+	if !certified {
+		t.Fatal("no certification made by the named key has been found")
+	}
+
+	certifyMustFail := func(keyName, publicKey, identity string) {
+		reqCertify := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "certify/" + keyName,
+			Data: map[string]interface{}{
+				"public_key": publicKey,
+				"identity":   identity,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), reqCertify)
+		if !resp.IsError() {
+			t.Fatalf("expected to fail, keyname: %s, identity: %s", keyName, identity)
+		}
+	}
+
+	certifyMustFail("doNotExist", gpgPublicKey, "")
+	certifyMustFail("test", "Not ASCII armored", "")
+	certifyMustFail("test", gpgPublicKey, "Unknown identity")
+}
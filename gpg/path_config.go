@@ -0,0 +1,311 @@
+package gpg
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"regexp"
+	"strings"
+)
+
+// minRSABits is the smallest RSA key size that can be configured.
+const minRSABits = 1024
+
+// defaultMaxInputBytes is the default size limit of the data sent to the cryptographic operations.
+const defaultMaxInputBytes = 32 * 1024 * 1024
+
+// defaultMaxOutputBytes is the default size limit of the plaintext returned by the decryption operations.
+const defaultMaxOutputBytes = 32 * 1024 * 1024
+
+var supportedAlgorithms = []string{"rsa", "ecdsa", "eddsa"}
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"min_rsa_bits": {
+				Type:        framework.TypeInt,
+				Default:     2048,
+				Description: "The minimum number of bits of the RSA keys. Defaults to 2048.",
+			},
+			"default_rsa_bits": {
+				Type:        framework.TypeInt,
+				Default:     2048,
+				Description: "The number of bits of the RSA keys when key_bits is not provided. Defaults to 2048 or to min_rsa_bits if it is larger.",
+			},
+			"allowed_algorithms": {
+				Type:        framework.TypeCommaStringSlice,
+				Default:     supportedAlgorithms,
+				Description: `The public key algorithms allowed for the keys. Defaults to "rsa", "ecdsa" and "eddsa".`,
+			},
+			"allow_seeded_keys": {
+				Type:        framework.TypeBool,
+				Description: "Enables the generation of deterministic keys from a seed. Unsafe, must only be used for testing purposes.",
+			},
+			"allow_keyserver_import": {
+				Type:        framework.TypeBool,
+				Description: "Enables the import of public keys from a keyserver.",
+			},
+			"key_name_prefix": {
+				Type:        framework.TypeString,
+				Description: "The prefix the names of the created keys must start with.",
+			},
+			"key_name_pattern": {
+				Type:        framework.TypeString,
+				Description: "A regular expression the names of the created keys must fully match.",
+			},
+			"max_output_bytes": {
+				Type:        framework.TypeInt,
+				Default:     defaultMaxOutputBytes,
+				Description: "The maximum size in bytes of the plaintext returned by the decrypt endpoint, batch results included. Defaults to 32 MiB.",
+			},
+			"require_delete_confirmation": {
+				Type:        framework.TypeBool,
+				Description: "Requires confirm to be true to delete a key.",
+			},
+			"track_key_usage": {
+				Type:        framework.TypeBool,
+				Description: "Counts the sign, encrypt and decrypt operations made with each key, returned as usage_count and last_used when reading the key.",
+			},
+			"max_input_bytes": {
+				Type:        framework.TypeInt,
+				Default:     defaultMaxInputBytes,
+				Description: "The maximum size in bytes of the encoded data sent to the sign, verify, encrypt, decrypt and seal endpoints, batch inputs included. Defaults to 32 MiB.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWrite,
+			},
+		},
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) config(ctx context.Context, s logical.Storage) (*configEntry, error) {
+	entry, err := s.Get(ctx, "config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &configEntry{
+			MinRSABits:        2048,
+			DefaultRSABits:    2048,
+			AllowedAlgorithms: append([]string{}, supportedAlgorithms...),
+			MaxInputBytes:     defaultMaxInputBytes,
+			MaxOutputBytes:    defaultMaxOutputBytes,
+		}, nil
+	}
+
+	var config configEntry
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	// Configurations stored before default_rsa_bits existed
+	if config.DefaultRSABits == 0 {
+		config.DefaultRSABits = 2048
+	}
+	// Configurations stored before max_input_bytes existed
+	if config.MaxInputBytes == 0 {
+		config.MaxInputBytes = defaultMaxInputBytes
+	}
+	// Configurations stored before max_output_bytes existed
+	if config.MaxOutputBytes == 0 {
+		config.MaxOutputBytes = defaultMaxOutputBytes
+	}
+	return &config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"min_rsa_bits":                config.MinRSABits,
+			"default_rsa_bits":            config.DefaultRSABits,
+			"allowed_algorithms":          config.AllowedAlgorithms,
+			"allow_seeded_keys":           config.AllowSeededKeys,
+			"allow_keyserver_import":      config.AllowKeyserverImport,
+			"key_name_prefix":             config.KeyNamePrefix,
+			"key_name_pattern":            config.KeyNamePattern,
+			"max_input_bytes":             config.MaxInputBytes,
+			"max_output_bytes":            config.MaxOutputBytes,
+			"require_delete_confirmation": config.RequireDeleteConfirmation,
+			"track_key_usage":             config.TrackKeyUsage,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	// Only the fields given in the request are changed, the others keep their stored value
+	if minRSABits, ok := data.GetOk("min_rsa_bits"); ok {
+		config.MinRSABits = minRSABits.(int)
+	}
+	if defaultRSABits, ok := data.GetOk("default_rsa_bits"); ok {
+		config.DefaultRSABits = defaultRSABits.(int)
+	}
+	if allowedAlgorithms, ok := data.GetOk("allowed_algorithms"); ok {
+		config.AllowedAlgorithms = allowedAlgorithms.([]string)
+	}
+	if allowSeededKeys, ok := data.GetOk("allow_seeded_keys"); ok {
+		config.AllowSeededKeys = allowSeededKeys.(bool)
+	}
+	if allowKeyserverImport, ok := data.GetOk("allow_keyserver_import"); ok {
+		config.AllowKeyserverImport = allowKeyserverImport.(bool)
+	}
+	if keyNamePrefix, ok := data.GetOk("key_name_prefix"); ok {
+		config.KeyNamePrefix = keyNamePrefix.(string)
+	}
+	if keyNamePattern, ok := data.GetOk("key_name_pattern"); ok {
+		config.KeyNamePattern = keyNamePattern.(string)
+	}
+	if maxInputBytes, ok := data.GetOk("max_input_bytes"); ok {
+		config.MaxInputBytes = maxInputBytes.(int)
+	}
+	if maxOutputBytes, ok := data.GetOk("max_output_bytes"); ok {
+		config.MaxOutputBytes = maxOutputBytes.(int)
+	}
+	if requireDeleteConfirmation, ok := data.GetOk("require_delete_confirmation"); ok {
+		config.RequireDeleteConfirmation = requireDeleteConfirmation.(bool)
+	}
+	if trackKeyUsage, ok := data.GetOk("track_key_usage"); ok {
+		config.TrackKeyUsage = trackKeyUsage.(bool)
+	}
+	if config.MinRSABits < minRSABits {
+		return logical.ErrorResponse(fmt.Sprintf("invalid min_rsa_bits %d; must be at least %d", config.MinRSABits, minRSABits)), logical.ErrInvalidRequest
+	}
+	if _, ok := data.GetOk("default_rsa_bits"); !ok && config.DefaultRSABits < config.MinRSABits {
+		config.DefaultRSABits = config.MinRSABits
+	}
+	if config.DefaultRSABits < config.MinRSABits {
+		return logical.ErrorResponse(fmt.Sprintf("invalid default_rsa_bits %d; must be at least min_rsa_bits %d", config.DefaultRSABits, config.MinRSABits)), logical.ErrInvalidRequest
+	}
+	if len(config.AllowedAlgorithms) == 0 {
+		return logical.ErrorResponse("at least one algorithm must be allowed"), logical.ErrInvalidRequest
+	}
+	for _, algorithm := range config.AllowedAlgorithms {
+		if !strutil.StrListContains(supportedAlgorithms, algorithm) {
+			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s; must be \"rsa\", \"ecdsa\" or \"eddsa\"", algorithm)), logical.ErrInvalidRequest
+		}
+	}
+	if _, err := regexp.Compile(config.KeyNamePattern); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid key_name_pattern: %s", err)), logical.ErrInvalidRequest
+	}
+	if config.MaxInputBytes <= 0 {
+		return logical.ErrorResponse(fmt.Sprintf("invalid max_input_bytes %d; must be positive", config.MaxInputBytes)), logical.ErrInvalidRequest
+	}
+	if config.MaxOutputBytes <= 0 {
+		return logical.ErrorResponse(fmt.Sprintf("invalid max_output_bytes %d; must be positive", config.MaxOutputBytes)), logical.ErrInvalidRequest
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+type configEntry struct {
+	MinRSABits                int      `json:"min_rsa_bits"`
+	DefaultRSABits            int      `json:"default_rsa_bits"`
+	AllowedAlgorithms         []string `json:"allowed_algorithms"`
+	AllowSeededKeys           bool     `json:"allow_seeded_keys"`
+	AllowKeyserverImport      bool     `json:"allow_keyserver_import"`
+	KeyNamePrefix             string   `json:"key_name_prefix"`
+	KeyNamePattern            string   `json:"key_name_pattern"`
+	MaxInputBytes             int      `json:"max_input_bytes"`
+	MaxOutputBytes            int      `json:"max_output_bytes"`
+	RequireDeleteConfirmation bool     `json:"require_delete_confirmation"`
+	TrackKeyUsage             bool     `json:"track_key_usage"`
+}
+
+// check returns an error if a key with the given algorithm and size is not allowed by the configuration.
+func (config *configEntry) check(algorithm string, keyBits int) error {
+	if !strutil.StrListContains(config.AllowedAlgorithms, algorithm) {
+		return fmt.Errorf("algorithm %s is not allowed; allowed algorithms are %s", algorithm, strings.Join(config.AllowedAlgorithms, ", "))
+	}
+	if algorithm == "rsa" && keyBits < config.MinRSABits {
+		return fmt.Errorf("RSA keys < %d bits are not allowed by the min_rsa_bits configuration", config.MinRSABits)
+	}
+	return nil
+}
+
+// rsaBits returns the number of bits of a generated RSA key, key_bits if it is provided.
+func (config *configEntry) rsaBits(data *framework.FieldData) int {
+	if keyBits, ok := data.GetOk("key_bits"); ok {
+		return keyBits.(int)
+	}
+	return config.DefaultRSABits
+}
+
+// checkInputSize returns an error if the inputs of an operation are larger than the max_input_bytes configuration.
+func (config *configEntry) checkInputSize(inputs ...string) error {
+	size := 0
+	for _, input := range inputs {
+		size += len(input)
+	}
+	if size > config.MaxInputBytes {
+		return fmt.Errorf("input of %d bytes exceeds the max_input_bytes limit of %d bytes", size, config.MaxInputBytes)
+	}
+	return nil
+}
+
+// checkInputSize returns an error response if the inputs of an operation are larger than the max_input_bytes
+// configuration.
+func (b *backend) checkInputSize(ctx context.Context, s logical.Storage, inputs ...string) (*logical.Response, error) {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if err = config.checkInputSize(inputs...); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// checkName returns an error if a key cannot be created with the given name.
+func (config *configEntry) checkName(name string) error {
+	if err := checkReservedName(name); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(name, config.KeyNamePrefix) {
+		return fmt.Errorf("key name %s is not allowed; key names must start with %q", name, config.KeyNamePrefix)
+	}
+	if config.KeyNamePattern == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile("^(?:" + config.KeyNamePattern + ")$")
+	if err != nil {
+		return err
+	}
+	if !pattern.MatchString(name) {
+		return fmt.Errorf("key name %s is not allowed; key names must match the pattern %q", name, config.KeyNamePattern)
+	}
+	return nil
+}
+
+const pathConfigHelpSyn = "Configure the policy applied to the GPG keys"
+
+const pathConfigHelpDesc = `
+This path is used to configure the minimum and default sizes of the RSA keys and the
+public key algorithms allowed when keys are created, as well as the prefix
+or pattern the names of the keys must follow. It also controls whether
+deterministic keys can be generated from a seed, which must only be enabled
+for testing purposes, and whether public keys can be imported from a keyserver.
+A write only changes the fields given in the request.
+`
@@ -0,0 +1,360 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGPG_Config(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	readConfig := func() map[string]interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "config",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+	writeConfig := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	createKey := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/test",
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	expected := map[string]interface{}{
+		"min_rsa_bits":                2048,
+		"default_rsa_bits":            2048,
+		"allowed_algorithms":          []string{"rsa", "ecdsa", "eddsa"},
+		"allow_seeded_keys":           false,
+		"allow_keyserver_import":      false,
+		"key_name_prefix":             "",
+		"key_name_pattern":            "",
+		"max_input_bytes":             defaultMaxInputBytes,
+		"max_output_bytes":            defaultMaxOutputBytes,
+		"require_delete_confirmation": false,
+		"track_key_usage":             false,
+	}
+	if config := readConfig(); !reflect.DeepEqual(config, expected) {
+		t.Fatalf("expected default configuration %#v, got %#v", expected, config)
+	}
+
+	if resp := writeConfig(map[string]interface{}{"min_rsa_bits": 512}); !resp.IsError() {
+		t.Fatal("expected to fail, min_rsa_bits is too small")
+	}
+	if resp := writeConfig(map[string]interface{}{"allowed_algorithms": "rsa,dsa"}); !resp.IsError() {
+		t.Fatal("expected to fail, dsa is not supported")
+	}
+	if resp := writeConfig(map[string]interface{}{"min_rsa_bits": 3072, "allowed_algorithms": "rsa,eddsa"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	expected = map[string]interface{}{
+		"min_rsa_bits":                3072,
+		"default_rsa_bits":            3072,
+		"allowed_algorithms":          []string{"rsa", "eddsa"},
+		"allow_seeded_keys":           false,
+		"allow_keyserver_import":      false,
+		"key_name_prefix":             "",
+		"key_name_pattern":            "",
+		"max_input_bytes":             defaultMaxInputBytes,
+		"max_output_bytes":            defaultMaxOutputBytes,
+		"require_delete_confirmation": false,
+		"track_key_usage":             false,
+	}
+	if config := readConfig(); !reflect.DeepEqual(config, expected) {
+		t.Fatalf("expected configuration %#v, got %#v", expected, config)
+	}
+
+	resp := createKey(map[string]interface{}{"real_name": "Vault GPG test", "key_bits": 2048})
+	if !resp.IsError() {
+		t.Fatal("expected to fail, key_bits is smaller than min_rsa_bits")
+	}
+	if !strings.Contains(resp.Data["error"].(string), "3072") {
+		t.Fatalf("expected the error to name the configured minimum, got %s", resp.Data["error"])
+	}
+	if resp = createKey(map[string]interface{}{"real_name": "Vault GPG test", "algorithm": "ecdsa"}); !resp.IsError() {
+		t.Fatal("expected to fail, ecdsa is not allowed")
+	}
+	resp = createKey(map[string]interface{}{"generate": false, "key": gpgKey})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if len(resp.Warnings) != 2 || !strings.Contains(resp.Warnings[0], "min_rsa_bits") {
+		t.Fatalf("expected warnings about the imported key smaller than min_rsa_bits, got %#v", resp.Warnings)
+	}
+	if _, err := b.HandleRequest(context.Background(), &logical.Request{Storage: storage, Operation: logical.DeleteOperation, Path: "keys/test"}); err != nil {
+		t.Fatal(err)
+	}
+	if resp = createKey(map[string]interface{}{"real_name": "Vault GPG test", "algorithm": "eddsa"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	reqSubkey := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "subkey/test",
+		Data: map[string]interface{}{
+			"algorithm": "ecdsa",
+		},
+	}
+	resp, _ = b.HandleRequest(context.Background(), reqSubkey)
+	if !resp.IsError() {
+		t.Fatal("expected to fail, ecdsa subkeys are not allowed")
+	}
+
+	if resp = writeConfig(map[string]interface{}{"min_rsa_bits": 1024}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp = createKey(map[string]interface{}{"real_name": "Vault GPG test", "key_bits": 1024, "force": true}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	// A write only changes the given fields
+	if resp = writeConfig(map[string]interface{}{"allow_keyserver_import": true, "key_name_prefix": "app-"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp = writeConfig(map[string]interface{}{"track_key_usage": true}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	config := readConfig()
+	if config["min_rsa_bits"] != 1024 || !reflect.DeepEqual(config["allowed_algorithms"], []string{"rsa", "eddsa"}) {
+		t.Fatalf("expected min_rsa_bits and allowed_algorithms to be kept, got %#v", config)
+	}
+	if config["allow_keyserver_import"] != true || config["key_name_prefix"] != "app-" || config["track_key_usage"] != true {
+		t.Fatalf("expected the previously written fields to be kept, got %#v", config)
+	}
+	if resp = writeConfig(map[string]interface{}{"allow_keyserver_import": false}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if config = readConfig(); config["allow_keyserver_import"] != false || config["track_key_usage"] != true {
+		t.Fatalf("expected only allow_keyserver_import to be disabled, got %#v", config)
+	}
+}
+
+func TestGPG_ConfigKeyName(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	writeConfig := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	createKey := func(name string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"real_name": "Vault GPG test",
+				"algorithm": "eddsa",
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := writeConfig(map[string]interface{}{"key_name_pattern": "svc-("}); !resp.IsError() {
+		t.Fatal("expected to fail, the pattern is not a valid regular expression")
+	}
+	if resp := writeConfig(map[string]interface{}{"key_name_prefix": "svc-", "key_name_pattern": "[a-z-]+"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	resp := createKey("app")
+	if !resp.IsError() {
+		t.Fatal("expected to fail, the key name does not start with the prefix")
+	}
+	if !strings.Contains(resp.Data["error"].(string), "svc-") {
+		t.Fatalf("expected the error to name the required prefix, got %s", resp.Data["error"])
+	}
+	if resp = createKey("svc-app1"); !resp.IsError() {
+		t.Fatal("expected to fail, the key name does not match the pattern")
+	}
+	if resp = createKey("svc-app"); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+}
+
+func TestGPG_ConfigDefaultRSABits(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	writeConfig := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	createKey := func(path string, data map[string]interface{}) int {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		entry, err := b.key(context.Background(), storage, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		entity, err := b.entity(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bitLength, err := entity.Subkeys[len(entity.Subkeys)-1].PublicKey.BitLength()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(bitLength)
+	}
+
+	if resp := writeConfig(map[string]interface{}{"min_rsa_bits": 3072, "default_rsa_bits": 2048}); !resp.IsError() {
+		t.Fatal("expected to fail, default_rsa_bits is smaller than min_rsa_bits")
+	}
+	if resp := writeConfig(map[string]interface{}{"default_rsa_bits": 3072}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	if bits := createKey("keys/test", map[string]interface{}{"real_name": "Vault GPG test"}); bits != 3072 {
+		t.Fatalf("expected a key of the default 3072 bits, got %d", bits)
+	}
+	if bits := createKey("subkey/test", map[string]interface{}{"algorithm": "rsa"}); bits != 3072 {
+		t.Fatalf("expected a subkey of the default 3072 bits, got %d", bits)
+	}
+	if bits := createKey("keys/test", map[string]interface{}{"real_name": "Vault GPG test", "key_bits": 2048, "force": true}); bits != 2048 {
+		t.Fatalf("expected key_bits to override the default, got %d", bits)
+	}
+}
+
+func TestGPG_ConfigMaxInputBytes(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := handle("config", map[string]interface{}{"max_input_bytes": 0}); !resp.IsError() {
+		t.Fatal("expected to fail, max_input_bytes must be positive")
+	}
+	if resp := handle("config", map[string]interface{}{"max_input_bytes": 16}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	handle("keys/test", map[string]interface{}{"real_name": "Vault GPG test", "algorithm": "eddsa"})
+
+	small := "dGhlIGZveA=="
+	large := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	operations := map[string]map[string]interface{}{
+		"sign/test":    {"input": large},
+		"verify/test":  {"input": small, "signature": large},
+		"encrypt/test": {"plaintext": large},
+		"decrypt/test": {"batch_input": []string{small, small}},
+		"seal/test":    {"plaintext": large, "recipient_key": "not checked"},
+	}
+	for path, data := range operations {
+		resp := handle(path, data)
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), "max_input_bytes") {
+			t.Fatalf("expected %s to fail, the input exceeds max_input_bytes, got %#v", path, resp)
+		}
+	}
+	if resp := handle("sign/test", map[string]interface{}{"input": small}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+}
+
+func TestGPG_ConfigMaxOutputBytes(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := handle("config", map[string]interface{}{"max_output_bytes": 0}); !resp.IsError() {
+		t.Fatal("expected to fail, max_output_bytes must be positive")
+	}
+	if resp := handle("config", map[string]interface{}{"max_output_bytes": 16}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	handle("keys/test", map[string]interface{}{"real_name": "Vault GPG test", "algorithm": "eddsa"})
+
+	// Plaintexts of 7 and 19 bytes
+	small := handle("encrypt/test", map[string]interface{}{"plaintext": "dGhlIGZveA==", "format": "base64"}).Data["ciphertext"].(string)
+	large := handle("encrypt/test", map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "format": "base64"}).Data["ciphertext"].(string)
+
+	resp := handle("decrypt/test", map[string]interface{}{"ciphertext": large})
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "max_output_bytes") {
+		t.Fatalf("expected to fail, the plaintext exceeds max_output_bytes, got %#v", resp)
+	}
+	if resp = handle("decrypt/test", map[string]interface{}{"ciphertext": small}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	resp = handle("decrypt/test", map[string]interface{}{"batch_input": []string{small, small, small}})
+	results := resp.Data["batch_results"].([]map[string]interface{})
+	if results[0]["error"] != nil || results[1]["error"] != nil {
+		t.Fatalf("expected the first plaintexts to fit in max_output_bytes, got %#v", results)
+	}
+	if err, _ := results[2]["error"].(string); !strings.Contains(err, "max_output_bytes") {
+		t.Fatalf("expected the plaintexts of the batch to be added up, got %#v", results[2])
+	}
+}
@@ -4,18 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
-	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/armor"
 	"io"
 	"strings"
 )
 
 func pathDecrypt(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "decrypt/" + framework.GenericNameRegex("name"),
+		Pattern: "decrypt/" + keyNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
 				Type:        framework.TypeString,
@@ -34,10 +37,27 @@ func pathDecrypt(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "The ASCII-armored GPG key of the signer of the ciphertext. If present, the signature must be valid.",
 			},
+			"allow_expired": {
+				Type:        framework.TypeBool,
+				Description: "Accepts the signature of a signer key that has expired. Only used with signer_key.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+			"symmetric_passphrase": {
+				Type:        framework.TypeString,
+				Description: "If set, the ciphertext is decrypted with this passphrase instead of the named key, which does not need to exist.",
+			},
+			"batch_input": {
+				Type:        framework.TypeStringSlice,
+				Description: "A list of ciphertexts to decrypt. When set, ciphertext is ignored and a plaintext or an error is returned for each item, in the same order.",
+			},
+			"context": operationContextField(),
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
-				Callback: b.pathDecryptWrite,
+				Callback: withMetrics("decrypt", b.withUsageTracking(withOperationContext(b.pathDecryptWrite))),
 			},
 		},
 		HelpSynopsis:    pathDecryptHelpSyn,
@@ -46,6 +66,9 @@ func pathDecrypt(b *backend) *framework.Path {
 }
 
 func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if resp, err := b.checkInputSize(ctx, req.Storage, append(data.Get("batch_input").([]string), data.Get("ciphertext").(string))...); resp != nil || err != nil {
+		return resp, err
+	}
 	format := data.Get("format").(string)
 	switch format {
 	case "base64":
@@ -54,18 +77,34 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
 	}
 
-	keyEntry, err := b.key(ctx, req.Storage, data.Get("name").(string))
-	if err != nil {
-		return nil, err
-	}
-	if keyEntry == nil {
-		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
-	}
+	symmetricPassphrase := data.Get("symmetric_passphrase").(string)
+	var keyring openpgp.EntityList
+	if symmetricPassphrase == "" {
+		keyEntry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if keyEntry == nil {
+			return keyNotFound(data.Get("name").(string))
+		}
+		markKeyUsed(ctx, data.Get("name").(string))
+		if err = keyEntry.checkUsable(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if resp, err := b.checkRateLimit(data.Get("name").(string), keyEntry, len(data.Get("batch_input").([]string))); resp != nil || err != nil {
+			return resp, err
+		}
 
-	r := bytes.NewReader(keyEntry.SerializedKey)
-	keyring, err := openpgp.ReadKeyRing(r)
-	if err != nil {
-		return nil, err
+		keyring, err = b.keyring(keyEntry)
+		if err != nil {
+			return nil, err
+		}
+		if err = decryptKeyringPrivateKeys(keyring, data.Get("passphrase").(string)); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if !canDecrypt(keyring[0]) {
+			return logical.ErrorResponse("the key does not have an encryption capable key or subkey"), logical.ErrInvalidRequest
+		}
 	}
 
 	signerKey := data.Get("signer_key").(string)
@@ -77,47 +116,189 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, da
 		keyring = append(keyring, el[0])
 	}
 
-	ciphertextEncoded := strings.NewReader(data.Get("ciphertext").(string))
-	var ciphertextDecoder io.Reader
-	switch format {
-	case "base64":
-		ciphertextDecoder = base64.NewDecoder(base64.StdEncoding, ciphertextEncoded)
-	case "ascii-armor":
-		block, err := armor.Decode(ciphertextEncoded)
-		if err != nil {
-			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if batchInput := data.Get("batch_input").([]string); len(batchInput) > 0 {
+		batchResults := make([]map[string]interface{}, 0, len(batchInput))
+		// The limit applies to the plaintexts of the batch added up
+		remainingOutputBytes := config.MaxOutputBytes
+		for _, ciphertext := range batchInput {
+			message, err := decrypt(keyring, ciphertext, format, signerKey != "", data.Get("allow_expired").(bool), symmetricPassphrase, remainingOutputBytes)
+			if err != nil {
+				batchResults = append(batchResults, map[string]interface{}{
+					"error": err.Error(),
+				})
+				continue
+			}
+			remainingOutputBytes -= message.size
+			batchResults = append(batchResults, message.data())
 		}
-		ciphertextDecoder = block.Body
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"batch_results": batchResults,
+			},
+		}, nil
 	}
 
-	md, err := openpgp.ReadMessage(ciphertextDecoder, keyring, nil, nil)
+	message, err := decrypt(keyring, data.Get("ciphertext").(string), format, signerKey != "", data.Get("allow_expired").(bool), symmetricPassphrase, config.MaxOutputBytes)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
+	return &logical.Response{
+		Data: message.data(),
+	}, nil
+}
+
+// decryptedMessage is the result of the decryption of a ciphertext.
+type decryptedMessage struct {
+	// plaintext is base64-encoded
+	plaintext string
+	// size is the size in bytes of the decoded plaintext
+	size int
+	// fingerprint is the fingerprint of the primary key that decrypted the message, empty if a passphrase did
+	fingerprint string
+	// decryptionFingerprint is the fingerprint of the key or subkey that decrypted the message
+	decryptionFingerprint string
+	// recipientKeyIDs are the key IDs the message is encrypted for
+	recipientKeyIDs []string
+}
+
+// data returns the fields of the decrypted message returned in the responses.
+func (m decryptedMessage) data() map[string]interface{} {
+	data := map[string]interface{}{
+		"plaintext":         m.plaintext,
+		"recipient_key_ids": m.recipientKeyIDs,
+	}
+	if m.fingerprint != "" {
+		data["key_fingerprint"] = m.fingerprint
+		data["decryption_key_fingerprint"] = m.decryptionFingerprint
+	}
+	return data
+}
+
+// decodeCiphertext returns a reader of the binary message encoded in the given format.
+func decodeCiphertext(ciphertext string, format string) (io.Reader, error) {
+	ciphertextEncoded := strings.NewReader(ciphertext)
+	if format == "base64" {
+		return base64.NewDecoder(base64.StdEncoding, ciphertextEncoded), nil
+	}
+	block, err := armor.Decode(ciphertextEncoded)
+	if err != nil {
+		return nil, armorError("ciphertext", err)
+	}
+	return block.Body, nil
+}
+
+// ciphertextError adds a hint to the errors caused by a truncated or corrupted armored ciphertext, which are only
+// seen while reading the message.
+func ciphertextError(err error, format string) error {
+	if format == "ascii-armor" && (err == io.EOF || err == io.ErrUnexpectedEOF || err == armor.ArmorCorrupt) {
+		return armorError("ciphertext", err)
+	}
+	return err
+}
+
+// messageRecipients returns the key IDs of the recipients listed by the public key encrypted session key packets
+// at the beginning of the message.
+func messageRecipients(ciphertext string, format string) []string {
+	recipientKeyIDs := []string{}
+	ciphertextDecoder, err := decodeCiphertext(ciphertext, format)
+	if err != nil {
+		return recipientKeyIDs
+	}
+	packets := packet.NewReader(ciphertextDecoder)
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return recipientKeyIDs
+		}
+		encryptedKey, ok := p.(*packet.EncryptedKey)
+		if !ok {
+			return recipientKeyIDs
+		}
+		recipientKeyIDs = append(recipientKeyIDs, fmt.Sprintf("%016x", encryptedKey.KeyId))
+	}
+}
+
+// decrypt decrypts a ciphertext encoded in the given format with the keyring, or with the symmetric passphrase if it
+// is not empty. When signed is true, the ciphertext must be signed by one of the keys of the keyring, which may have
+// expired only if allowExpired is true. It fails if the plaintext is larger than maxOutputBytes.
+func decrypt(keyring openpgp.EntityList, ciphertext string, format string, signed bool, allowExpired bool, symmetricPassphrase string, maxOutputBytes int) (*decryptedMessage, error) {
+	ciphertextDecoder, err := decodeCiphertext(ciphertext, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var prompt openpgp.PromptFunction
+	if symmetricPassphrase != "" {
+		prompted := false
+		prompt = func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+			// ReadMessage prompts again as long as the passphrase is incorrect
+			if prompted || !symmetric {
+				return nil, errors.ErrKeyIncorrect
+			}
+			prompted = true
+			return []byte(symmetricPassphrase), nil
+		}
+	}
+
+	md, err := openpgp.ReadMessage(ciphertextDecoder, keyring, prompt, nil)
+	if err == errors.ErrKeyIncorrect {
+		if symmetricPassphrase != "" {
+			return nil, fmt.Errorf("the passphrase is incorrect or the message is not encrypted with a passphrase")
+		}
+		// The recipients are listed to help finding out which key the message was meant for.
+		if recipientKeyIDs := messageRecipients(ciphertext, format); len(recipientKeyIDs) > 0 {
+			return nil, fmt.Errorf("the message is not encrypted for the key or any of its subkeys; it is encrypted for the key IDs %s", strings.Join(recipientKeyIDs, ", "))
+		}
+		return nil, fmt.Errorf("the message is not encrypted for the key or any of its subkeys")
+	}
+	if err != nil {
+		return nil, ciphertextError(err, format)
+	}
+
 	var plaintext bytes.Buffer
 	w := base64.NewEncoder(base64.StdEncoding, &plaintext)
-	if _, err = io.Copy(w, md.UnverifiedBody); err != nil {
-		return nil, err
+	// The body is read up to one byte past the limit to find out if the plaintext is too large without buffering it
+	// entirely.
+	size, err := io.Copy(w, io.LimitReader(md.UnverifiedBody, int64(maxOutputBytes)+1))
+	if err != nil {
+		return nil, ciphertextError(err, format)
+	}
+	if size > int64(maxOutputBytes) {
+		return nil, fmt.Errorf("plaintext exceeds the max_output_bytes limit of %d bytes", maxOutputBytes)
 	}
 	if err = w.Close(); err != nil {
 		return nil, err
 	}
 
-	if signerKey != "" && (!md.IsSigned || md.SignedBy == nil || md.SignatureError != nil) {
-		return logical.ErrorResponse("Signature is invalid or not present"), nil
+	if signed && (!md.IsSigned || md.SignedBy == nil || (md.SignatureError != nil && !(allowExpired && md.SignatureError == errors.ErrKeyExpired))) {
+		return nil, fmt.Errorf("Signature is invalid or not present")
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"plaintext": plaintext.String(),
-		},
-	}, nil
+	message := &decryptedMessage{
+		plaintext:       plaintext.String(),
+		size:            int(size),
+		recipientKeyIDs: make([]string, 0, len(md.EncryptedToKeyIds)),
+	}
+	for _, keyID := range md.EncryptedToKeyIds {
+		message.recipientKeyIDs = append(message.recipientKeyIDs, fmt.Sprintf("%016x", keyID))
+	}
+	if md.DecryptedWith.Entity != nil {
+		message.fingerprint = hex.EncodeToString(md.DecryptedWith.Entity.PrimaryKey.Fingerprint[:])
+		message.decryptionFingerprint = hex.EncodeToString(md.DecryptedWith.PublicKey.Fingerprint)
+	}
+	return message, nil
 }
 
 const pathDecryptHelpSyn = "Decrypt a ciphertext value using a named GPG key"
 
 const pathDecryptHelpDesc = `
 This path uses the named GPG key from the request path to decrypt a user
-provided ciphertext. The plaintext is returned base64 encoded.
+provided ciphertext or a batch of ciphertexts. The plaintext is returned
+base64 encoded.
 `
@@ -0,0 +1,238 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+	"strings"
+	"time"
+)
+
+func pathEncrypt(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "encrypt/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+			"plaintext": {
+				Type:        framework.TypeString,
+				Description: "The base64-encoded plaintext to encrypt",
+			},
+			"format": {
+				Type:        framework.TypeString,
+				Default:     "ascii-armor",
+				Description: `Encoding format to use for the ciphertext. Can be "base64" or "ascii-armor". Defaults to "ascii-armor".`,
+			},
+			"compression": {
+				Type:        framework.TypeString,
+				Default:     "zlib",
+				Description: `Compression algorithm to use if the key supports it. Can be "none", "zip" or "zlib". Defaults to "zlib".`,
+			},
+			"cipher": {
+				Type:        framework.TypeString,
+				Default:     "aes256",
+				Description: `Symmetric cipher to use if the key supports it. Can be "aes128", "aes192" or "aes256". Defaults to "aes256".`,
+			},
+			"allow_expired": {
+				Type:        framework.TypeBool,
+				Description: "Allows to use the key even if it or its encryption subkeys expired.",
+			},
+			"symmetric_passphrase": {
+				Type:        framework.TypeString,
+				Description: "If set, the plaintext is encrypted with this passphrase instead of the named key, which does not need to exist.",
+			},
+			"aead": {
+				Type:        framework.TypeBool,
+				Description: "If true, the message is encrypted with AEAD in a version 2 symmetrically encrypted integrity protected data packet (RFC 9580). The key, unless symmetric_passphrase is used, must advertise support for it.",
+			},
+			"signer": {
+				Type:        framework.TypeBool,
+				Description: "If true, the message is also signed with the named key.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the message is signed and the key is protected by a passphrase.",
+			},
+			"context": operationContextField(),
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: withMetrics("encrypt", b.withUsageTracking(withOperationContext(b.pathEncryptWrite))),
+			},
+		},
+		HelpSynopsis:    pathEncryptHelpSyn,
+		HelpDescription: pathEncryptHelpDesc,
+	}
+}
+
+func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if resp, err := b.checkInputSize(ctx, req.Storage, data.Get("plaintext").(string)); resp != nil || err != nil {
+		return resp, err
+	}
+	format := data.Get("format").(string)
+	switch format {
+	case "base64":
+	case "ascii-armor":
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
+	}
+
+	config := &packet.Config{}
+	switch compression := data.Get("compression").(string); compression {
+	case "none":
+		config.DefaultCompressionAlgo = packet.CompressionNone
+	case "zip":
+		config.DefaultCompressionAlgo = packet.CompressionZIP
+	case "zlib":
+		config.DefaultCompressionAlgo = packet.CompressionZLIB
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported compression %s; must be \"none\", \"zip\" or \"zlib\"", compression)), nil
+	}
+	switch cipher := data.Get("cipher").(string); cipher {
+	case "aes128":
+		config.DefaultCipher = packet.CipherAES128
+	case "aes192":
+		config.DefaultCipher = packet.CipherAES192
+	case "aes256":
+		config.DefaultCipher = packet.CipherAES256
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported cipher %s; must be \"aes128\", \"aes192\" or \"aes256\"", cipher)), nil
+	}
+	aead := data.Get("aead").(bool)
+	if aead {
+		config.AEADConfig = &packet.AEADConfig{}
+	}
+
+	symmetricPassphrase := data.Get("symmetric_passphrase").(string)
+	var entity, signer *openpgp.Entity
+	if symmetricPassphrase != "" {
+		if data.Get("signer").(bool) {
+			return logical.ErrorResponse("signer cannot be used with symmetric_passphrase"), logical.ErrInvalidRequest
+		}
+	} else {
+		entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return keyNotFound(data.Get("name").(string))
+		}
+		markKeyUsed(ctx, data.Get("name").(string))
+		if err = entry.checkEnabled(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		entity, err = b.entity(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !canEncrypt(entity) {
+			return logical.ErrorResponse("no encryption key available, the key does not have an encryption capable key or subkey"), logical.ErrInvalidRequest
+		}
+		if data.Get("signer").(bool) && !canSign(entity) {
+			return logical.ErrorResponse("no signing key available, the key does not have a signing capable key or subkey"), logical.ErrInvalidRequest
+		}
+		allowExpired := data.Get("allow_expired").(bool)
+		if allowExpired {
+			ignoreExpiration(entity)
+		} else if err = checkExpiration(entity, time.Now(), false); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if _, ok := entity.EncryptionKey(time.Now()); !ok {
+			return logical.ErrorResponse("the key does not have a valid encryption key or subkey"), logical.ErrInvalidRequest
+		}
+		if aead {
+			if err = checkAEADSupport(entity); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+		if data.Get("signer").(bool) {
+			// Signing the message is subject to the same restrictions as the sign operation.
+			if err = entry.checkUsageTTL(); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			if resp, err := b.checkRateLimit(data.Get("name").(string), entry, 1); resp != nil || err != nil {
+				return resp, err
+			}
+			if !allowExpired {
+				if err = checkExpiration(entity, time.Now(), true); err != nil {
+					return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+				}
+			}
+			if err = decryptPrivateKeys(entity, data.Get("passphrase").(string)); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			signer = entity
+		}
+	}
+
+	var ciphertext bytes.Buffer
+	var ciphertextEncoder io.WriteCloser
+	var err error
+	switch format {
+	case "base64":
+		ciphertextEncoder = base64.NewEncoder(base64.StdEncoding, &ciphertext)
+	case "ascii-armor":
+		ciphertextEncoder, err = armor.Encode(&ciphertext, "PGP MESSAGE", nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hints := &openpgp.FileHints{IsBinary: true}
+	var w io.WriteCloser
+	if symmetricPassphrase != "" {
+		w, err = openpgp.SymmetricallyEncrypt(ciphertextEncoder, []byte(symmetricPassphrase), hints, config)
+	} else {
+		w, err = openpgp.Encrypt(ciphertextEncoder, []*openpgp.Entity{entity}, signer, hints, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	plaintext := base64.NewDecoder(base64.StdEncoding, strings.NewReader(data.Get("plaintext").(string)))
+	if _, err = io.Copy(w, plaintext); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to decode plaintext as base64: %s", err)), logical.ErrInvalidRequest
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	if err = ciphertextEncoder.Close(); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"ciphertext": ciphertext.String(),
+		},
+	}
+	if entity != nil {
+		resp.Data["key_fingerprint"] = hex.EncodeToString(entity.PrimaryKey.Fingerprint[:])
+	}
+	return resp, nil
+}
+
+// checkAEADSupport returns an error if the key does not advertise support for AEAD encrypted messages. The
+// messages encrypted for such a key silently fall back to version 1 encrypted packets without AEAD.
+func checkAEADSupport(entity *openpgp.Entity) error {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if selfSignature == nil || !selfSignature.SEIPDv2 {
+		return fmt.Errorf("the key does not advertise support for AEAD encryption (SEIPD v2), set aead to false to encrypt for it")
+	}
+	return nil
+}
+
+const pathEncryptHelpSyn = "Encrypt a plaintext value using a named GPG key"
+
+const pathEncryptHelpDesc = `
+This path uses the named GPG key from the request path to encrypt a user
+provided plaintext. The plaintext must be base64 encoded.
+`
@@ -0,0 +1,533 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"strings"
+	"testing"
+)
+
+func TestGPG_EncryptDecrypt(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqRead := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := resp.Data["public_key"].(string)
+
+	encryptDecrypt := func(format string, signer bool) {
+		reqEncrypt := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext": "QWxwYWNhcwo=",
+				"format":    format,
+				"signer":    signer,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), reqEncrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		ciphertext, ok := resp.Data["ciphertext"]
+		if !ok {
+			t.Fatalf("no ciphertext found in response data %#v", resp.Data)
+		}
+
+		reqDecrypt := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "decrypt/test",
+			Data: map[string]interface{}{
+				"ciphertext": ciphertext,
+				"format":     format,
+			},
+		}
+		if signer {
+			reqDecrypt.Data["signer_key"] = publicKey
+		}
+		resp, err = b.HandleRequest(context.Background(), reqDecrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		if resp.Data["plaintext"] != "QWxwYWNhcwo=" {
+			t.Fatalf("expected plaintext QWxwYWNhcwo=, got: %s", resp.Data["plaintext"])
+		}
+	}
+
+	encryptDecrypt("ascii-armor", false)
+	encryptDecrypt("base64", false)
+	encryptDecrypt("ascii-armor", true)
+}
+
+func TestGPG_EncryptError(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptMustFail := func(keyName, plaintext, format string) {
+		reqEncrypt := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/" + keyName,
+			Data: map[string]interface{}{
+				"plaintext": plaintext,
+				"format":    format,
+			},
+		}
+
+		resp, _ := b.HandleRequest(context.Background(), reqEncrypt)
+		if !resp.IsError() {
+			t.Fatalf("expected to fail, keyname: %s, format: %s, plaintext: %s", keyName, format, plaintext)
+		}
+	}
+
+	encryptMustFail("doNotExist", "QWxwYWNhcwo=", "ascii-armor")
+	encryptMustFail("test", "QWxwYWNhcwo=", "invalidFormat")
+	encryptMustFail("test", "Not base64 encoded", "ascii-armor")
+}
+
+func TestGPG_EncryptCompression(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := base64.StdEncoding.EncodeToString(make([]byte, 16384))
+	encrypt := func(compression string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/test",
+			Data: map[string]interface{}{
+				"plaintext":   plaintext,
+				"format":      "base64",
+				"compression": compression,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := encrypt("bzip2"); !resp.IsError() {
+		t.Fatal("expected to fail, bzip2 is not supported")
+	}
+	uncompressed := encrypt("none").Data["ciphertext"].(string)
+	compressed := encrypt("zlib").Data["ciphertext"].(string)
+	if len(compressed) >= len(uncompressed)/10 {
+		t.Fatalf("expected the message to be compressed, got %d bytes instead of %d", len(compressed), len(uncompressed))
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "decrypt/test",
+		Data: map[string]interface{}{
+			"ciphertext": compressed,
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["plaintext"] != plaintext {
+		t.Fatal("the compressed message has not been decrypted to the plaintext")
+	}
+}
+
+func TestGPG_EncryptDecryptSymmetric(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	plaintext := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	if resp := handle("encrypt/nokey", map[string]interface{}{
+		"plaintext":            plaintext,
+		"symmetric_passphrase": "secret",
+		"signer":               true,
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, a symmetrically encrypted message cannot be signed")
+	}
+	if resp := handle("encrypt/nokey", map[string]interface{}{
+		"plaintext":            plaintext,
+		"symmetric_passphrase": "secret",
+		"cipher":               "3des",
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, 3des is not supported")
+	}
+
+	for _, cipher := range []string{"aes128", "aes192", "aes256"} {
+		resp := handle("encrypt/nokey", map[string]interface{}{
+			"plaintext":            plaintext,
+			"format":               "base64",
+			"symmetric_passphrase": "secret",
+			"cipher":               cipher,
+		})
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		ciphertext := resp.Data["ciphertext"]
+
+		resp = handle("decrypt/nokey", map[string]interface{}{
+			"ciphertext":           ciphertext,
+			"symmetric_passphrase": "secret",
+		})
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("the message encrypted with %s has not been decrypted to the plaintext", cipher)
+		}
+
+		if resp = handle("decrypt/nokey", map[string]interface{}{
+			"ciphertext":           ciphertext,
+			"symmetric_passphrase": "wrong",
+		}); !resp.IsError() {
+			t.Fatal("expected to fail, the passphrase is incorrect")
+		}
+		if resp = handle("decrypt/nokey", map[string]interface{}{
+			"ciphertext": ciphertext,
+		}); !resp.IsError() {
+			t.Fatal("expected to fail, the key does not exist")
+		}
+	}
+
+	handle("keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	resp := handle("encrypt/test", map[string]interface{}{
+		"plaintext": plaintext,
+		"format":    "base64",
+	})
+	if resp = handle("decrypt/test", map[string]interface{}{
+		"ciphertext":           resp.Data["ciphertext"],
+		"symmetric_passphrase": "secret",
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, the message is not encrypted with a passphrase")
+	}
+}
+
+func TestGPG_OperationsReturnKeyFingerprint(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	fingerprint := handle(logical.ReadOperation, "keys/test", nil).Data["fingerprint"]
+
+	resp := handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{
+		"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		"format":    "base64",
+	})
+	if resp.Data["key_fingerprint"] != fingerprint {
+		t.Fatalf("expected the encryption key fingerprint %s, got %s", fingerprint, resp.Data["key_fingerprint"])
+	}
+	ciphertext := resp.Data["ciphertext"]
+
+	handle(logical.UpdateOperation, "rotate/test", nil)
+	rotatedFingerprint := handle(logical.ReadOperation, "keys/test", nil).Data["fingerprint"]
+
+	resp = handle(logical.UpdateOperation, "decrypt/test", map[string]interface{}{
+		"ciphertext": ciphertext,
+	})
+	if resp.Data["key_fingerprint"] != fingerprint {
+		t.Fatalf("expected the fingerprint %s of the previous key that decrypted the message, got %s", fingerprint, resp.Data["key_fingerprint"])
+	}
+	resp = handle(logical.UpdateOperation, "decrypt/test", map[string]interface{}{
+		"batch_input": []string{ciphertext.(string)},
+	})
+	if result := resp.Data["batch_results"].([]map[string]interface{})[0]; result["key_fingerprint"] != fingerprint {
+		t.Fatalf("expected the fingerprint %s in the batch result, got %s", fingerprint, result["key_fingerprint"])
+	}
+
+	resp = handle(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+	})
+	if resp.Data["key_fingerprint"] != rotatedFingerprint {
+		t.Fatalf("expected the signing key fingerprint %s, got %s", rotatedFingerprint, resp.Data["key_fingerprint"])
+	}
+
+	resp = handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{
+		"plaintext":            "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		"symmetric_passphrase": "secret",
+	})
+	if _, ok := resp.Data["key_fingerprint"]; ok {
+		t.Fatal("expected no key fingerprint when encrypting with a passphrase")
+	}
+}
+
+func TestGPG_EncryptAEAD(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	encryptedVersion := func(ciphertext string) int {
+		raw, err := base64.StdEncoding.DecodeString(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets := packet.NewReader(bytes.NewReader(raw))
+		for {
+			p, err := packets.Next()
+			if err != nil {
+				t.Fatalf("no encrypted data packet found: %s", err)
+			}
+			if encrypted, ok := p.(*packet.SymmetricallyEncrypted); ok {
+				return encrypted.Version
+			}
+		}
+	}
+
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "", &packet.Config{
+		Algorithm:  packet.PubKeyAlgoEdDSA,
+		AEADConfig: &packet.AEADConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if resp := handle("keys/aead", map[string]interface{}{
+		"generate": false,
+		"key":      key.String(),
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	handle("keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+		"aead":      true,
+	})
+	handle("keys/legacy", map[string]interface{}{
+		"generate": false,
+		"key":      gpgKey,
+	})
+	handle("keys/default", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	handle("rotate/test", nil)
+	handle("rotate/default", nil)
+
+	reqRead := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/aead",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	tests := map[string]map[string]interface{}{
+		"encrypt/aead":  {"plaintext": plaintext},
+		"encrypt/nokey": {"plaintext": plaintext, "symmetric_passphrase": "secret"},
+		"seal/aead":     {"plaintext": plaintext},
+		"seal/test":     {"plaintext": plaintext, "recipient_key": resp.Data["public_key"]},
+		"encrypt/test":  {"plaintext": plaintext},
+	}
+	for path, data := range tests {
+		data["format"] = "base64"
+		data["aead"] = true
+		resp := handle(path, data)
+		if resp.IsError() {
+			t.Fatalf("not expected error response for %s: %#v", path, *resp)
+		}
+		if version := encryptedVersion(resp.Data["ciphertext"].(string)); version != 2 {
+			t.Fatalf("expected a version 2 encrypted data packet for %s, got version %d", path, version)
+		}
+		decryptData := map[string]interface{}{"ciphertext": resp.Data["ciphertext"], "format": "base64"}
+		decryptPath := "decrypt/aead"
+		if path == "encrypt/test" {
+			decryptPath = "decrypt/test"
+		}
+		if secret, ok := data["symmetric_passphrase"]; ok {
+			decryptData["symmetric_passphrase"] = secret
+			decryptPath = "decrypt/nokey"
+		}
+		if resp = handle(decryptPath, decryptData); resp.IsError() || resp.Data["plaintext"] != plaintext {
+			t.Fatalf("expected the message encrypted with %s to be decrypted to the plaintext, got %#v", path, resp)
+		}
+	}
+
+	if resp := handle("encrypt/legacy", map[string]interface{}{"plaintext": plaintext, "aead": true}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not advertise support for AEAD")
+	}
+	if resp := handle("seal/legacy", map[string]interface{}{"plaintext": plaintext, "aead": true}); !resp.IsError() {
+		t.Fatal("expected to fail, the recipient key does not advertise support for AEAD")
+	}
+	if resp := handle("encrypt/default", map[string]interface{}{"plaintext": plaintext, "aead": true}); !resp.IsError() {
+		t.Fatal("expected to fail, the keys generated without aead do not advertise support for AEAD")
+	}
+}
+
+func TestGPG_OperationsWithoutCapableKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	mustFail := func(path string, data map[string]interface{}, expected string) {
+		resp := handle(path, data)
+		if !resp.IsError() {
+			t.Fatalf("expected %s to fail", path)
+		}
+		if err := resp.Error().Error(); !strings.HasPrefix(err, expected) {
+			t.Fatalf("expected %s to fail with %q, got %q", path, expected, err)
+		}
+	}
+
+	// An encryption-only key, the primary key only certifies.
+	handle("keys/encryption", map[string]interface{}{
+		"real_name":     "Vault GPG test",
+		"algorithm":     "eddsa",
+		"primary_flags": "certify",
+	})
+	// A signing-only key, without any encryption subkey.
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity.Subkeys = nil
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if resp := handle("keys/signing", map[string]interface{}{
+		"generate": false,
+		"key":      key.String(),
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	input := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	mustFail("sign/encryption", map[string]interface{}{"input": input}, "no signing key available")
+	mustFail("sign-digest/encryption", map[string]interface{}{"digest": "5ebe2294ecd0e0f08eab7690d2a6ee6926ae2d6a9bfd0cb7d8fc9d8d00a0ed24"}, "no signing key available")
+	mustFail("seal/encryption", map[string]interface{}{"plaintext": input}, "no signing key available")
+	mustFail("encrypt/encryption", map[string]interface{}{"plaintext": input, "signer": true}, "no signing key available")
+	mustFail("encrypt/signing", map[string]interface{}{"plaintext": input}, "no encryption key available")
+	mustFail("seal/signing", map[string]interface{}{"plaintext": input}, "no encryption key available")
+
+	if resp := handle("sign/signing", map[string]interface{}{"input": input}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp := handle("encrypt/encryption", map[string]interface{}{"plaintext": input}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+}
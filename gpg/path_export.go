@@ -3,20 +3,31 @@ package gpg
 import (
 	"bytes"
 	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
-	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/armor"
+	"time"
 )
 
+// exportWrapTTL is the TTL of the wrapping token used when the request does not ask for a specific one.
+const exportWrapTTL = 5 * time.Minute
+
 func pathExportKeys(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "export/" + framework.GenericNameRegex("name"),
+		Pattern: "export/" + keyNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
 				Type:        framework.TypeString,
 				Description: "Name of the key",
 			},
+			"line_ending": {
+				Type:        framework.TypeString,
+				Default:     "lf",
+				Description: `The line ending of the armored key. Can be "lf" or "crlf". Defaults to "lf".`,
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -30,34 +41,69 @@ func pathExportKeys(b *backend) *framework.Path {
 
 func (b *backend) pathExportKeyRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
+	lineEnding := data.Get("line_ending").(string)
+	if err := checkLineEnding(lineEnding); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
 	entry, err := b.key(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
 	if entry == nil {
-		return nil, nil
+		return keyNotFound(name)
 	}
 	if !entry.Exportable {
 		return logical.ErrorResponse("key is not exportable"), nil
 	}
 
+	blockType, err := keyBlockType(entry.SerializedKey)
+	if err != nil {
+		return nil, err
+	}
+
 	var buf bytes.Buffer
-	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	w, err := armor.Encode(&buf, blockType, keyArmorHeaders(name))
 	if err != nil {
 		return nil, err
 	}
-	w.Write(entry.SerializedKey)
-	if w.Close() != nil {
+	if _, err = w.Write(entry.SerializedKey); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
 		return nil, err
 	}
 
-	return &logical.Response{
+	// The private key is always response-wrapped so it can only be retrieved once.
+	// The key is exported as stored, a passphrase-protected key stays encrypted.
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"name": name,
-			"key":  buf.String(),
+			"key":  withLineEnding(buf.String(), lineEnding),
 		},
-	}, nil
+	}
+	if req.WrapInfo == nil || req.WrapInfo.TTL == 0 {
+		resp.WrapInfo = &wrapping.ResponseWrapInfo{
+			TTL: exportWrapTTL,
+		}
+	}
+	return resp, nil
+}
+
+// keyBlockType returns the armor block type of a serialized key. It is read from the stored packets rather than from
+// the parsed entity, the keys using a PKCS#11 token are stored without their private key but are parsed with one.
+func keyBlockType(serialized []byte) (string, error) {
+	p, err := packet.Read(bytes.NewReader(serialized))
+	if err != nil {
+		return "", err
+	}
+	if _, ok := p.(*packet.PrivateKey); ok {
+		return openpgp.PrivateKeyType, nil
+	}
+	return openpgp.PublicKeyType, nil
 }
 
 const pathExportHelpSyn = "Export named GPG key"
-const pathExportHelpDesc = "This path is used to export the keys that are configured as exportable."
+const pathExportHelpDesc = `
+This path is used to export the keys that are configured as exportable.
+The response is always wrapped, the requested wrapping TTL is used if any.
+`
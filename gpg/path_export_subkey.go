@@ -0,0 +1,140 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+)
+
+func pathExportSubkey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "export_subkey/" + keyNameRegex("name") + "/" + framework.GenericNameRegex("fingerprint"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"fingerprint": {
+				Type:        framework.TypeString,
+				Description: "The hex-encoded fingerprint of the subkey to export.",
+			},
+			"include_primary_identity": {
+				Type:        framework.TypeBool,
+				Description: "Includes the primary identity of the key so the exported key can be imported by implementations requiring an identity, such as GnuPG.",
+			},
+			"line_ending": {
+				Type:        framework.TypeString,
+				Default:     "lf",
+				Description: `The line ending of the armored key. Can be "lf" or "crlf". Defaults to "lf".`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathExportSubkeyRead,
+			},
+		},
+		HelpSynopsis:    pathExportSubkeyHelpSyn,
+		HelpDescription: pathExportSubkeyHelpDesc,
+	}
+}
+
+func (b *backend) pathExportSubkeyRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	lineEnding := data.Get("line_ending").(string)
+	if err := checkLineEnding(lineEnding); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(name)
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	subkey, ok := findSubkey(entity, data.Get("fingerprint").(string))
+	if !ok {
+		return logical.ErrorResponse("subkey not found"), logical.ErrInvalidRequest
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, keyArmorHeaders(name))
+	if err != nil {
+		return nil, err
+	}
+	var identity *openpgp.Identity
+	if data.Get("include_primary_identity").(bool) {
+		identity = entity.PrimaryIdentity()
+	}
+	if err = serializeSubkeyOnly(w, entity, subkey, identity); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"fingerprint": hex.EncodeToString(subkey.PublicKey.Fingerprint[:]),
+			"public_key":  withLineEnding(buf.String(), lineEnding),
+		},
+	}, nil
+}
+
+// serializeSubkeyOnly writes the public primary key of the entity with its revocations and direct signatures, the
+// identity with its self-signature if it is not nil, and the given subkey with its binding signature. The other
+// identities and subkeys are left out.
+func serializeSubkeyOnly(w io.Writer, entity *openpgp.Entity, subkey *openpgp.Subkey, identity *openpgp.Identity) error {
+	if err := entity.PrimaryKey.Serialize(w); err != nil {
+		return err
+	}
+	for _, revocation := range entity.Revocations {
+		if err := revocation.Serialize(w); err != nil {
+			return err
+		}
+	}
+	for _, directSignature := range entity.Signatures {
+		if err := directSignature.Serialize(w); err != nil {
+			return err
+		}
+	}
+	if identity != nil {
+		if err := identity.UserId.Serialize(w); err != nil {
+			return err
+		}
+		if err := identity.SelfSignature.Serialize(w); err != nil {
+			return err
+		}
+	}
+	if err := subkey.PublicKey.Serialize(w); err != nil {
+		return err
+	}
+	// The binding signature comes before the revocations as required by RFC 4880 section 11.1
+	if err := subkey.Sig.Serialize(w); err != nil {
+		return err
+	}
+	for _, revocation := range subkey.Revocations {
+		if err := revocation.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const pathExportSubkeyHelpSyn = "Export the public part of a subkey of a named GPG key"
+
+const pathExportSubkeyHelpDesc = `
+This path exports the public primary key of the named GPG key with only the
+requested subkey and its binding signature, leaving out the identities and
+the other subkeys. It is meant to distribute an encryption subkey without
+disclosing the rest of the key.
+`
@@ -0,0 +1,94 @@
+package gpg
+
+import (
+	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGPG_ExportSubkey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"algorithm": "eddsa",
+		"identities": []interface{}{
+			map[string]interface{}{"real_name": "Vault GPG test", "email": "vault@example.com"},
+			map[string]interface{}{"real_name": "Vault GPG test", "email": "other@example.com"},
+		},
+	})
+	handle(logical.UpdateOperation, "subkey/test", map[string]interface{}{"usage": "sign"})
+	subkeys := handle(logical.ReadOperation, "keys/test", nil).Data["subkeys"].([]map[string]interface{})
+	fingerprint := subkeys[0]["fingerprint"].(string)
+
+	if resp := handle(logical.ReadOperation, "export_subkey/test/0000000000000000000000000000000000000000", nil); !resp.IsError() {
+		t.Fatal("expected to fail, the subkey does not exist")
+	}
+	if resp := handle(logical.ReadOperation, "export_subkey/notfound/"+fingerprint, nil); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not exist")
+	}
+
+	resp := handle(logical.ReadOperation, "export_subkey/test/"+strings.ToUpper(fingerprint), nil)
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["fingerprint"] != fingerprint {
+		t.Fatalf("expected fingerprint %s, got %s", fingerprint, resp.Data["fingerprint"])
+	}
+	block, err := armor.Decode(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var publicKeys, userIds int
+	packets := packet.NewReader(block.Body)
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch p.(type) {
+		case *packet.PublicKey:
+			publicKeys++
+		case *packet.UserId:
+			userIds++
+		}
+	}
+	if publicKeys != 2 || userIds != 0 {
+		t.Fatalf("expected the primary key and the subkey without identity, got %d keys and %d identities", publicKeys, userIds)
+	}
+
+	resp = handle(logical.ReadOperation, "export_subkey/test/"+fingerprint, map[string]interface{}{"include_primary_identity": true})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(el[0].Identities) != 1 || len(el[0].Subkeys) != 1 {
+		t.Fatalf("expected a single identity and subkey, got %d identities and %d subkeys", len(el[0].Identities), len(el[0].Subkeys))
+	}
+	if _, ok := el[0].EncryptionKey(time.Now()); !ok {
+		t.Fatal("expected the exported subkey to be usable for encryption")
+	}
+}
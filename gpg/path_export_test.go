@@ -2,8 +2,12 @@ package gpg
 
 import (
 	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/hashicorp/vault/sdk/logical"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGPG_ExportNotExistingKeyReturnsNotFound(t *testing.T) {
@@ -18,9 +22,12 @@ func TestGPG_ExportNotExistingKeyReturnsNotFound(t *testing.T) {
 	}
 	rsp, err := b.HandleRequest(context.Background(), req)
 
-	if !(rsp == nil && err == nil) {
+	if !rsp.IsError() || rsp.Error().Error() != "key not found: test" {
 		t.Fatal("Key does not exist but does not return not found")
 	}
+	if codedErr, ok := err.(logical.HTTPCodedError); !ok || codedErr.Code() != http.StatusNotFound {
+		t.Fatalf("expected a 404 error, got %#v", err)
+	}
 }
 
 func TestGPG_ExportNotExportableKeyReturnsNotFound(t *testing.T) {
@@ -93,4 +100,116 @@ func TestGPG_ExportKey(t *testing.T) {
 	if name != "test" {
 		t.Fatalf("not expected name, expected test got: %s", name)
 	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != exportWrapTTL {
+		t.Fatalf("expected the response to be wrapped with a TTL of %s, got %#v", exportWrapTTL, resp.WrapInfo)
+	}
+
+	reqExp.WrapInfo = &logical.RequestWrapInfo{
+		TTL: time.Minute,
+	}
+	resp, err = b.HandleRequest(context.Background(), reqExp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.WrapInfo != nil {
+		t.Fatalf("expected the requested wrapping TTL to be used, got %#v", resp.WrapInfo)
+	}
+}
+
+func TestGPG_ExportPassphraseProtectedKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name":  "Vault GPG test",
+			"exportable": true,
+			"passphrase": "passphrase",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqExp := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "export/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqExp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !el[0].PrivateKey.Encrypted {
+		t.Fatal("the exported private key must stay encrypted")
+	}
+	if err = el[0].DecryptPrivateKeys([]byte("passphrase")); err != nil {
+		t.Fatalf("the exported private key cannot be decrypted with the passphrase: %s", err)
+	}
+}
+
+func TestGPG_ExportArmorBlockType(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp.IsError() {
+			t.Fatalf("not expected error on %s: %#v, %s", path, resp, err)
+		}
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/private", map[string]interface{}{
+		"real_name":  "Vault GPG test",
+		"exportable": true,
+	})
+	handle(logical.UpdateOperation, "keys/protected", map[string]interface{}{
+		"real_name":  "Vault GPG test",
+		"exportable": true,
+		"passphrase": "passphrase",
+	})
+	read := handle(logical.ReadOperation, "keys/private", nil)
+	handle(logical.UpdateOperation, "keys/public", map[string]interface{}{
+		"generate":          false,
+		"key":               read.Data["public_key"],
+		"exportable":        true,
+		"allow_public_only": true,
+	})
+	subkey := read.Data["subkeys"].([]map[string]interface{})[0]["fingerprint"].(string)
+
+	privateHeader := "-----BEGIN " + openpgp.PrivateKeyType + "-----"
+	publicHeader := "-----BEGIN " + openpgp.PublicKeyType + "-----"
+	exports := []struct {
+		path   string
+		field  string
+		header string
+	}{
+		{"export/private", "key", privateHeader},
+		{"export/protected", "key", privateHeader},
+		{"export/public", "key", publicHeader},
+		{"keys/private", "public_key", publicHeader},
+		{"export_subkey/private/" + subkey, "public_key", publicHeader},
+	}
+	for _, export := range exports {
+		key := handle(logical.ReadOperation, export.path, nil).Data[export.field].(string)
+		if !strings.HasPrefix(key, export.header) {
+			t.Fatalf("expected %s to start with %s, got %s", export.path, export.header, key)
+		}
+	}
 }
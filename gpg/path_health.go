@@ -0,0 +1,147 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"time"
+)
+
+func pathKeyHealth(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + keyNameRegex("name") + "/health",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathKeyHealthRead,
+			},
+		},
+		HelpSynopsis:    pathKeyHealthHelpSyn,
+		HelpDescription: pathKeyHealthHelpDesc,
+	}
+}
+
+func (b *backend) pathKeyHealthRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+
+	report := map[string]interface{}{
+		"parsed":                false,
+		"has_private_key":       false,
+		"private_key_encrypted": false,
+		"private_key_valid":     false,
+		"expired":               false,
+		"revoked":               false,
+		"self_signatures_valid": false,
+		"usage_ttl_expired":     false,
+		"enabled":               entry.Enabled,
+	}
+	reasons := []string{}
+	defer func() {
+		report["reasons"] = reasons
+		report["healthy"] = len(reasons) == 0
+	}()
+
+	if err := entry.checkEnabled(); err != nil {
+		reasons = append(reasons, err.Error())
+	}
+	if err := entry.checkUsageTTL(); err != nil {
+		report["usage_ttl_expired"] = true
+		reasons = append(reasons, err.Error())
+	}
+
+	keyring, err := b.keyring(entry)
+	if err != nil {
+		reasons = append(reasons, fmt.Sprintf("the key cannot be parsed: %s", err))
+		return &logical.Response{Data: report}, nil
+	}
+	report["parsed"] = true
+	entity := keyring[0]
+	now := time.Now()
+
+	if expiration, ok := keyExpiration(entity); ok && now.After(expiration) {
+		report["expired"] = true
+		reasons = append(reasons, fmt.Sprintf("key expired on %s", expiration.UTC().Format(time.RFC3339)))
+	}
+	if entity.Revoked(now) {
+		report["revoked"] = true
+		reasons = append(reasons, "the key is revoked")
+	}
+
+	if err := verifySelfSignatures(entity); err != nil {
+		reasons = append(reasons, err.Error())
+	} else {
+		report["self_signatures_valid"] = true
+	}
+
+	if entity.PrivateKey != nil {
+		report["has_private_key"] = true
+		// The private keys are validated when parsed, but encrypted ones cannot be checked without the passphrase.
+		if entity.PrivateKey.Encrypted {
+			report["private_key_encrypted"] = true
+		} else if err := checkSigning(entity, now); err != nil {
+			reasons = append(reasons, err.Error())
+		} else {
+			report["private_key_valid"] = true
+		}
+	}
+
+	return &logical.Response{Data: report}, nil
+}
+
+// verifySelfSignatures returns an error if a self-signature of an identity or a subkey binding
+// signature of the entity does not verify.
+func verifySelfSignatures(entity *openpgp.Entity) error {
+	for _, id := range entity.Identities {
+		if id.SelfSignature == nil {
+			return fmt.Errorf("the identity %q does not have a self-signature", id.Name)
+		}
+		if err := entity.PrimaryKey.VerifyUserIdSignature(id.Name, entity.PrimaryKey, id.SelfSignature); err != nil {
+			return fmt.Errorf("the self-signature of the identity %q is invalid: %s", id.Name, err)
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if err := entity.PrimaryKey.VerifyKeySignature(subkey.PublicKey, subkey.Sig); err != nil {
+			return fmt.Errorf("the binding signature of subkey %x is invalid: %s", subkey.PublicKey.Fingerprint, err)
+		}
+	}
+	return nil
+}
+
+// checkSigning makes and verifies a signature with the entity if it is able to sign.
+func checkSigning(entity *openpgp.Entity, now time.Time) error {
+	if _, ok := entity.SigningKey(now); !ok {
+		return nil
+	}
+	message := []byte("vault-gpg-plugin health check")
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, entity, bytes.NewReader(message), nil); err != nil {
+		return fmt.Errorf("unable to sign with the private key: %s", err)
+	}
+	if _, err := openpgp.CheckDetachedSignature(openpgp.EntityList{entity}, bytes.NewReader(message), &signature, nil); err != nil {
+		return fmt.Errorf("the signature made with the private key does not verify: %s", err)
+	}
+	return nil
+}
+
+const pathKeyHealthHelpSyn = "Check the health of a named GPG key"
+
+const pathKeyHealthHelpDesc = `
+This path checks the stored GPG key can be parsed, is not expired or revoked,
+has valid self-signatures and, when the private key is not protected by a
+passphrase, that it can make a valid signature. The report lists the reasons
+why the key is not healthy.
+`
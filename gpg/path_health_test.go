@@ -0,0 +1,97 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"testing"
+)
+
+func TestGPG_KeyHealth(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "config", map[string]interface{}{
+		"allow_seeded_keys": true,
+	})
+	handle(logical.UpdateOperation, "keys/healthy", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	handle(logical.UpdateOperation, "keys/protected", map[string]interface{}{
+		"real_name":  "Vault GPG test",
+		"algorithm":  "eddsa",
+		"passphrase": "secret",
+	})
+	handle(logical.UpdateOperation, "keys/expired", map[string]interface{}{
+		"real_name":   "Vault GPG test",
+		"algorithm":   "eddsa",
+		"seed":        "000102030405060708090a0b0c0d0e0f",
+		"key_expires": "24h",
+	})
+	corrupted, err := logical.StorageEntryJSON("key/corrupted", &keyEntry{SerializedKey: []byte("not a key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), corrupted); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]map[string]interface{}{
+		"healthy": {
+			"healthy":               true,
+			"parsed":                true,
+			"private_key_valid":     true,
+			"private_key_encrypted": false,
+			"expired":               false,
+		},
+		"protected": {
+			"healthy":               true,
+			"private_key_valid":     false,
+			"private_key_encrypted": true,
+		},
+		"expired": {
+			"healthy":               false,
+			"expired":               true,
+			"self_signatures_valid": true,
+		},
+		"corrupted": {
+			"healthy": false,
+			"parsed":  false,
+		},
+	}
+	for name, expected := range tests {
+		resp := handle(logical.ReadOperation, "keys/"+name+"/health", nil)
+		for field, value := range expected {
+			if resp.Data[field] != value {
+				t.Fatalf("expected %s to be %v for key %s, got %#v", field, value, name, resp.Data)
+			}
+		}
+		reasons := resp.Data["reasons"].([]string)
+		if healthy := resp.Data["healthy"].(bool); healthy != (len(reasons) == 0) {
+			t.Fatalf("expected reasons only when the key %s is not healthy, got %#v", name, reasons)
+		}
+	}
+
+	resp, _ := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/notfound/health",
+	})
+	if !resp.IsError() {
+		t.Fatalf("expected to fail for a key that does not exist, got %#v", resp)
+	}
+}
@@ -0,0 +1,191 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"time"
+)
+
+func pathIdentities(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + keyNameRegex("name") + "/identities",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"add": {
+				Type:        framework.TypeSlice,
+				Description: "A list of identities, each with a real_name, an email and a comment, to add to the key.",
+			},
+			"revoke": {
+				Type:        framework.TypeSlice,
+				Description: "A list of identities, each with a real_name, an email and a comment, to revoke. At least one identity of the key must remain.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathIdentitiesWrite,
+			},
+		},
+		HelpSynopsis:    pathIdentitiesHelpSyn,
+		HelpDescription: pathIdentitiesHelpDesc,
+	}
+}
+
+func (b *backend) pathIdentitiesWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	passphrase := data.Get("passphrase").(string)
+
+	added, err := parseIdentities(data.Get("add").([]interface{}))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	for _, id := range added {
+		if err = validateIdentity(id.realName, id.comment, id.email); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+	revoked, err := parseIdentities(data.Get("revoke").([]interface{}))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if len(added) == 0 && len(revoked) == 0 {
+		return logical.ErrorResponse("at least one identity to add or to revoke is required"), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(name)
+	}
+	if err = entry.checkKeyMaterialUpdatable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := entity.PrivateKey != nil && entity.PrivateKey.Encrypted
+	if err = decryptPrivateKeys(entity, passphrase); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	config := &packet.Config{}
+	for _, id := range revoked {
+		if err = revokeIdentity(entity, packet.NewUserId(id.realName, id.comment, id.email).Id, config); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+	for _, id := range added {
+		if err = addIdentity(entity, id, config); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+	if len(entityIdentities(entity)) == 0 {
+		return logical.ErrorResponse("at least one identity of the key must not be revoked"), logical.ErrInvalidRequest
+	}
+
+	if encrypted {
+		if err = entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if err = serializePrivateWithoutSigning(&buf, entity); err != nil {
+		return nil, err
+	}
+	entry.SerializedKey = buf.Bytes()
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+
+	identities := []map[string]interface{}{}
+	for _, id := range entityIdentities(entity) {
+		identities = append(identities, map[string]interface{}{
+			"real_name": id.UserId.Name,
+			"email":     id.UserId.Email,
+			"comment":   id.UserId.Comment,
+		})
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"identities": identities,
+		},
+	}, nil
+}
+
+// addIdentity adds a self-signed identity to the entity. The self-signature asserts the same capabilities and
+// validity period as the one of the primary identity so they are not changed by the new identity.
+func addIdentity(entity *openpgp.Entity, id identity, config *packet.Config) error {
+	primarySelfSignature, _ := entity.PrimarySelfSignature()
+	if primarySelfSignature == nil {
+		return fmt.Errorf("the key does not have a valid self-signature")
+	}
+	identityConfig := *config
+	if primarySelfSignature.KeyLifetimeSecs != nil {
+		identityConfig.KeyLifetimeSecs = *primarySelfSignature.KeyLifetimeSecs
+	}
+	if err := entity.AddUserId(id.realName, id.comment, id.email, &identityConfig); err != nil {
+		return err
+	}
+	added := entity.Identities[packet.NewUserId(id.realName, id.comment, id.email).Id]
+	added.SelfSignature.FlagCertify = primarySelfSignature.FlagCertify
+	added.SelfSignature.FlagSign = primarySelfSignature.FlagSign
+	added.SelfSignature.FlagAuthenticate = primarySelfSignature.FlagAuthenticate
+	added.SelfSignature.FlagEncryptCommunications = primarySelfSignature.FlagEncryptCommunications
+	added.SelfSignature.FlagEncryptStorage = primarySelfSignature.FlagEncryptStorage
+	return added.SelfSignature.SignUserId(added.UserId.Id, entity.PrimaryKey, entity.PrivateKey, &identityConfig)
+}
+
+// revokeIdentity adds a certification revocation signature to the identity of the entity matching the user ID.
+func revokeIdentity(entity *openpgp.Entity, userID string, config *packet.Config) error {
+	id, ok := entity.Identities[userID]
+	if !ok {
+		return fmt.Errorf("identity %q not found", userID)
+	}
+	if id.Revoked(time.Now()) {
+		return fmt.Errorf("identity %q is already revoked", userID)
+	}
+	reason := packet.UserIDNotValid
+	revocation := &packet.Signature{
+		Version:           entity.PrimaryKey.Version,
+		SigType:           packet.SigTypeCertificationRevocation,
+		PubKeyAlgo:        entity.PrimaryKey.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      config.Now(),
+		IssuerKeyId:       &entity.PrimaryKey.KeyId,
+		IssuerFingerprint: entity.PrimaryKey.Fingerprint,
+		RevocationReason:  &reason,
+	}
+	if err := revocation.SignUserId(userID, entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+		return err
+	}
+	id.Revocations = append(id.Revocations, revocation)
+	id.Signatures = append(id.Signatures, revocation)
+	return nil
+}
+
+const pathIdentitiesHelpSyn = "Add or revoke identities of a named GPG key"
+
+const pathIdentitiesHelpDesc = `
+This path adds self-signed identities to the named GPG key or revokes some of
+its identities, for example when an email changes. The key material is left
+untouched so the key does not have to be rotated and distributed again.
+Revoked identities are kept in the key so the revocation can be distributed.
+`
@@ -0,0 +1,92 @@
+package gpg
+
+import (
+	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGPG_Identities(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name":   "Vault GPG test",
+		"email":       "old@example.com",
+		"algorithm":   "eddsa",
+		"key_expires": "8760h",
+		"passphrase":  "passphrase",
+	})
+	before := handle(logical.ReadOperation, "keys/test", nil).Data
+
+	oldIdentity := map[string]interface{}{"real_name": "Vault GPG test", "email": "old@example.com"}
+	newIdentity := map[string]interface{}{"real_name": "Vault GPG test", "email": "new@example.com"}
+	failures := map[string]map[string]interface{}{
+		"nothing to do": {"passphrase": "passphrase"},
+		"no passphrase": {"add": []interface{}{newIdentity}},
+		"invalid email": {"add": []interface{}{map[string]interface{}{"email": "invalid"}}, "passphrase": "passphrase"},
+		"unknown":       {"revoke": []interface{}{newIdentity}, "passphrase": "passphrase"},
+		"revoke all":    {"revoke": []interface{}{oldIdentity}, "passphrase": "passphrase"},
+		"existing":      {"add": []interface{}{oldIdentity}, "passphrase": "passphrase"},
+	}
+	for name, data := range failures {
+		if resp := handle(logical.UpdateOperation, "keys/test/identities", data); !resp.IsError() {
+			t.Fatalf("expected to fail: %s", name)
+		}
+	}
+
+	resp := handle(logical.UpdateOperation, "keys/test/identities", map[string]interface{}{
+		"add":        []interface{}{newIdentity},
+		"revoke":     []interface{}{oldIdentity},
+		"passphrase": "passphrase",
+	})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	expected := []map[string]interface{}{{"real_name": "Vault GPG test", "email": "new@example.com", "comment": ""}}
+	if !reflect.DeepEqual(resp.Data["identities"], expected) {
+		t.Fatalf("expected identities %#v, got %#v", expected, resp.Data["identities"])
+	}
+
+	after := handle(logical.ReadOperation, "keys/test", nil).Data
+	for _, field := range []string{"fingerprint", "expires_at", "capabilities"} {
+		if !reflect.DeepEqual(after[field], before[field]) {
+			t.Fatalf("expected %s %v to be kept, got %v", field, before[field], after[field])
+		}
+	}
+	if !reflect.DeepEqual(after["identities"], expected) {
+		t.Fatalf("expected identities %#v, got %#v", expected, after["identities"])
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(after["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := el[0].Identities["Vault GPG test <old@example.com>"]; id == nil || !id.Revoked(time.Now()) {
+		t.Fatal("expected the old identity to be published revoked")
+	}
+	if id := el[0].PrimaryIdentity(); id.Name != "Vault GPG test <new@example.com>" {
+		t.Fatalf("expected the new identity to be the primary one, got %s", id.Name)
+	}
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	signature := handle(logical.UpdateOperation, "sign/test", map[string]interface{}{"input": input, "passphrase": "passphrase"}).Data["signature"]
+	if resp = handle(logical.UpdateOperation, "verify/test", map[string]interface{}{"input": input, "signature": signature}); !resp.Data["valid"].(bool) {
+		t.Fatal("expected the key to still sign after updating its identities")
+	}
+}
@@ -0,0 +1,93 @@
+package gpg
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+	"strings"
+)
+
+func pathInspect(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "inspect",
+		Fields: map[string]*framework.FieldSchema{
+			"ciphertext": {
+				Type:        framework.TypeString,
+				Description: "The ciphertext to inspect",
+			},
+			"format": {
+				Type:        framework.TypeString,
+				Default:     "base64",
+				Description: `Encoding format the ciphertext uses. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathInspectWrite,
+			},
+		},
+		HelpSynopsis:    pathInspectHelpSyn,
+		HelpDescription: pathInspectHelpDesc,
+	}
+}
+
+func (b *backend) pathInspectWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	format := data.Get("format").(string)
+	ciphertextEncoded := strings.NewReader(data.Get("ciphertext").(string))
+	var ciphertextDecoder io.Reader
+	switch format {
+	case "base64":
+		ciphertextDecoder = base64.NewDecoder(base64.StdEncoding, ciphertextEncoded)
+	case "ascii-armor":
+		block, err := armor.Decode(ciphertextEncoded)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		ciphertextDecoder = block.Body
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), logical.ErrInvalidRequest
+	}
+
+	// The session key packets come first, the parsing stops at the encrypted data.
+	recipientKeyIDs := []string{}
+	symmetric := false
+	packets := packet.NewReader(ciphertextDecoder)
+ParsePackets:
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("unable to read the message: %s", err)), logical.ErrInvalidRequest
+		}
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			recipientKeyIDs = append(recipientKeyIDs, fmt.Sprintf("%016x", p.KeyId))
+		case *packet.SymmetricKeyEncrypted:
+			symmetric = true
+		default:
+			break ParsePackets
+		}
+	}
+	if len(recipientKeyIDs) == 0 && !symmetric {
+		return logical.ErrorResponse("the message is not encrypted"), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"recipient_key_ids": recipientKeyIDs,
+			"symmetric":         symmetric,
+		},
+	}, nil
+}
+
+const pathInspectHelpSyn = "List the recipients of an encrypted message"
+
+const pathInspectHelpDesc = `
+This path parses the session key packets of an encrypted message and returns
+the key IDs the message is encrypted for, without decrypting it. A key ID of
+zero means the recipient is hidden.
+`
@@ -0,0 +1,68 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"testing"
+)
+
+func TestGPG_Inspect(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	subkeyFingerprint := handle(logical.ReadOperation, "keys/test", nil).Data["subkeys"].([]map[string]interface{})[0]["fingerprint"].(string)
+
+	resp := handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{
+		"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+	})
+	resp = handle(logical.UpdateOperation, "inspect", map[string]interface{}{
+		"ciphertext": resp.Data["ciphertext"],
+		"format":     "ascii-armor",
+	})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if expected := []string{subkeyFingerprint[len(subkeyFingerprint)-16:]}; !reflect.DeepEqual(resp.Data["recipient_key_ids"], expected) {
+		t.Fatalf("expected recipient key IDs %#v, got %#v", expected, resp.Data["recipient_key_ids"])
+	}
+	if resp.Data["symmetric"].(bool) {
+		t.Fatal("expected the message not to be encrypted with a passphrase")
+	}
+
+	resp = handle(logical.UpdateOperation, "encrypt/nokey", map[string]interface{}{
+		"plaintext":            "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		"format":               "base64",
+		"symmetric_passphrase": "secret",
+	})
+	resp = handle(logical.UpdateOperation, "inspect", map[string]interface{}{
+		"ciphertext": resp.Data["ciphertext"],
+	})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if !resp.Data["symmetric"].(bool) || len(resp.Data["recipient_key_ids"].([]string)) != 0 {
+		t.Fatalf("expected the message to only be encrypted with a passphrase, got %#v", resp.Data)
+	}
+
+	if resp = handle(logical.UpdateOperation, "inspect", map[string]interface{}{
+		"ciphertext": "Tm90IGEgbWVzc2FnZQ==",
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, the ciphertext is not an OpenPGP message")
+	}
+}
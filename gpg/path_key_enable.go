@@ -0,0 +1,87 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathKeyDisable(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + keyNameRegex("name") + "/disable",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeyDisableWrite,
+			},
+		},
+		HelpSynopsis:    pathKeyDisableHelpSyn,
+		HelpDescription: pathKeyDisableHelpDesc,
+	}
+}
+
+func pathKeyEnable(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + keyNameRegex("name") + "/enable",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeyEnableWrite,
+			},
+		},
+		HelpSynopsis:    pathKeyEnableHelpSyn,
+		HelpDescription: pathKeyEnableHelpDesc,
+	}
+}
+
+func (b *backend) pathKeyDisableWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.setKeyEnabled(ctx, req, data.Get("name").(string), false)
+}
+
+func (b *backend) pathKeyEnableWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.setKeyEnabled(ctx, req, data.Get("name").(string), true)
+}
+
+func (b *backend) setKeyEnabled(ctx context.Context, req *logical.Request, name string, enabled bool) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(name)
+	}
+
+	entry.Enabled = enabled
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathKeyDisableHelpSyn = "Disable a named GPG key"
+
+const pathKeyDisableHelpDesc = `
+This path disables the named GPG key without deleting it. A disabled key
+cannot be used to sign, certify, encrypt or decrypt until it is enabled
+again, signatures made with it can still be verified.
+`
+
+const pathKeyEnableHelpSyn = "Enable a named GPG key"
+
+const pathKeyEnableHelpDesc = `
+This path enables the named GPG key again after it has been disabled.
+`
@@ -0,0 +1,80 @@
+package gpg
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/hashicorp/vault/sdk/logical"
+	"testing"
+)
+
+func TestGPG_DisableEnableKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	readEnabled := func() interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/test",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data["enabled"]
+	}
+
+	handle("keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	if enabled := readEnabled(); enabled != true {
+		t.Fatalf("expected a new key to be enabled, got %#v", enabled)
+	}
+	input := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	signed := handle("sign/test", map[string]interface{}{"input": input})
+	if signed.IsError() {
+		t.Fatalf("not expected error response: %#v", *signed)
+	}
+
+	if resp := handle("keys/notfound/disable", nil); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not exist")
+	}
+	if resp := handle("keys/test/disable", nil); resp != nil {
+		t.Fatalf("not expected response: %#v", *resp)
+	}
+	if enabled := readEnabled(); enabled != false {
+		t.Fatalf("expected the key to be disabled, got %#v", enabled)
+	}
+	if resp := handle("sign/test", map[string]interface{}{"input": input}); !resp.IsError() {
+		t.Fatal("expected to fail, the key is disabled")
+	}
+	if resp := handle("encrypt/test", map[string]interface{}{"plaintext": input}); !resp.IsError() {
+		t.Fatal("expected to fail, the key is disabled")
+	}
+	resp := handle("verify/test", map[string]interface{}{
+		"input":     input,
+		"signature": signed.Data["signature"],
+	})
+	if resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected signatures to still be verified with a disabled key, got %#v", resp)
+	}
+
+	handle("keys/test/enable", nil)
+	if enabled := readEnabled(); enabled != true {
+		t.Fatalf("expected the key to be enabled, got %#v", enabled)
+	}
+	if resp := handle("sign/test", map[string]interface{}{"input": input}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+}
@@ -0,0 +1,65 @@
+package gpg
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathKeyRateLimit(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + keyNameRegex("name") + "/rate-limit",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"max_operations_per_second": {
+				Type:        framework.TypeInt,
+				Description: "The maximum number of sign and decrypt operations per second allowed with the key on each Vault node. A zero value means no limit.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathKeyRateLimitWrite,
+			},
+		},
+		HelpSynopsis:    pathKeyRateLimitHelpSyn,
+		HelpDescription: pathKeyRateLimitHelpDesc,
+	}
+}
+
+func (b *backend) pathKeyRateLimitWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	maxOperationsPerSecond := data.Get("max_operations_per_second").(int)
+	if maxOperationsPerSecond < 0 {
+		return logical.ErrorResponse(fmt.Sprintf("invalid max_operations_per_second %d; must not be negative", maxOperationsPerSecond)), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(name)
+	}
+
+	entry.MaxOperationsPerSecond = maxOperationsPerSecond
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathKeyRateLimitHelpSyn = "Limit the operations made with a named GPG key"
+
+const pathKeyRateLimitHelpDesc = `
+This path sets the maximum number of sign and decrypt operations per second
+allowed with the named GPG key. Requests exceeding the limit fail with a 429
+status code. The limit is enforced separately on each Vault node.
+`
@@ -0,0 +1,93 @@
+package gpg
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/hashicorp/vault/sdk/logical"
+	"net/http"
+	"testing"
+)
+
+func TestGPG_KeyRateLimit(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	if resp, _ := handle("keys/test", map[string]interface{}{
+		"real_name":                 "Vault GPG test",
+		"algorithm":                 "eddsa",
+		"max_operations_per_second": -1,
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, the maximum number of operations cannot be negative")
+	}
+	handle("keys/test", map[string]interface{}{
+		"real_name":                 "Vault GPG test",
+		"algorithm":                 "eddsa",
+		"max_operations_per_second": 1,
+	})
+	read, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Data["max_operations_per_second"] != 1 {
+		t.Fatalf("expected max_operations_per_second 1, got %#v", read.Data["max_operations_per_second"])
+	}
+
+	input := map[string]interface{}{"input": base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))}
+	if resp, _ := handle("sign/test", input); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	resp, err := handle("sign/test", input)
+	if !resp.IsError() {
+		t.Fatal("expected to fail, the key is limited to 1 operation per second")
+	}
+	if codedErr, ok := err.(logical.HTTPCodedError); !ok || codedErr.Code() != http.StatusTooManyRequests {
+		t.Fatalf("expected a %d error, got %#v", http.StatusTooManyRequests, err)
+	}
+	signedEncryption := map[string]interface{}{"plaintext": input["input"], "signer": true}
+	if resp, _ := handle("encrypt/test", signedEncryption); !resp.IsError() {
+		t.Fatal("expected to fail, signing through encrypt is also limited")
+	}
+
+	if resp, _ := handle("keys/notfound/rate-limit", map[string]interface{}{"max_operations_per_second": 0}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not exist")
+	}
+	if resp, _ := handle("keys/test/rate-limit", map[string]interface{}{"max_operations_per_second": -1}); !resp.IsError() {
+		t.Fatal("expected to fail, the maximum number of operations cannot be negative")
+	}
+	if resp, _ := handle("keys/test/rate-limit", map[string]interface{}{"max_operations_per_second": 0}); resp != nil {
+		t.Fatalf("not expected response: %#v", *resp)
+	}
+	if resp, _ := handle("sign/test", input); resp.IsError() {
+		t.Fatalf("expected the key to not be limited anymore, got %#v", *resp)
+	}
+
+	batch := []string{input["input"].(string), input["input"].(string), input["input"].(string)}
+	handle("keys/batch", map[string]interface{}{
+		"real_name":                 "Vault GPG test",
+		"algorithm":                 "eddsa",
+		"max_operations_per_second": 2,
+	})
+	if resp, _ := handle("sign/batch", map[string]interface{}{"batch_input": batch}); !resp.IsError() {
+		t.Fatal("expected to fail, the batch signs more inputs than the key is allowed per second")
+	}
+	if resp, _ := handle("sign/batch", map[string]interface{}{"batch_input": batch[:2]}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp, _ := handle("sign/batch", input); !resp.IsError() {
+		t.Fatal("expected to fail, the batch used all the operations allowed this second")
+	}
+}
@@ -3,20 +3,74 @@ package gpg
 import (
 	"bytes"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
-	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/armor"
-	"golang.org/x/crypto/openpgp/packet"
 	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
+// keyNameSegmentRegex matches a key name or the name of a folder of keys.
+const keyNameSegmentRegex = `\w(([\w-.]+)?\w)?`
+
+// keyNameRegex is like framework.GenericNameRegex but the names can be organized in folders separated by "/",
+// for example "team-a/signing". The folders are matched lazily so a fixed suffix can follow the name.
+func keyNameRegex(name string) string {
+	return fmt.Sprintf("(?P<%s>%s(/%s)*?)", name, keyNameSegmentRegex, keyNameSegmentRegex)
+}
+
+// keyEndpointNames are the endpoints under keys/:name, matched before the key names so the last part of a key name
+// in a folder cannot be one of them.
+var keyEndpointNames = []string{"health", "identities", "disable", "enable", "rate-limit", "add-encryption-subkey"}
+
+// checkReservedName returns an error if the key name collides with the endpoints under keys/, the key could not be
+// read afterwards.
+func checkReservedName(name string) error {
+	segments := strings.Split(name, "/")
+	if len(segments) == 1 {
+		return nil
+	}
+	if segments[0] == "by-fingerprint" {
+		return fmt.Errorf("key name %s is not allowed; a folder cannot be named by-fingerprint", name)
+	}
+	if last := segments[len(segments)-1]; strutil.StrListContains(keyEndpointNames, last) {
+		return fmt.Errorf("key name %s is not allowed; the last part of a key name in a folder cannot be %s", name, last)
+	}
+	return nil
+}
+
 func pathListKeys(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "keys/?$",
+		Pattern: "keys(/(?P<folder>(" + keyNameSegmentRegex + "/)*))?$",
+		Fields: map[string]*framework.FieldSchema{
+			"folder": {
+				Type:        framework.TypeString,
+				Description: "The folder to list the keys of, the keys of its subfolders are also listed.",
+			},
+			"detailed": {
+				Type:        framework.TypeBool,
+				Description: "If true, the fingerprint, the algorithm, the exportable flag and the tags of each key are also returned.",
+			},
+			"tag": {
+				Type:        framework.TypeString,
+				Description: `Only list the keys with this tag, given as "key:value" or as "key" to match any value.`,
+			},
+		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ListOperation: &framework.PathOperation{
 				Callback: b.pathKeyList,
@@ -27,44 +81,216 @@ func pathListKeys(b *backend) *framework.Path {
 	}
 }
 
+func pathKeysByFingerprint(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/by-fingerprint/" + framework.GenericNameRegex("fingerprint"),
+		Fields: map[string]*framework.FieldSchema{
+			"fingerprint": {
+				Type:        framework.TypeString,
+				Description: "The hex-encoded fingerprint of the primary key or of a subkey, current or previous.",
+			},
+			"export_format": {
+				Type:        framework.TypeString,
+				Default:     "armored",
+				Description: `The format of the returned public key. Can be "armored", "base64" for the binary key encoded in base64 or "ssh" for the OpenSSH public key of the authentication key. Defaults to "armored".`,
+			},
+			"line_ending": {
+				Type:        framework.TypeString,
+				Default:     "lf",
+				Description: `The line ending of the armored public key. Can be "lf" or "crlf". Defaults to "lf".`,
+			},
+			"include_subkeys": {
+				Type:        framework.TypeBool,
+				Default:     true,
+				Description: "Includes the subkeys in the returned public key. Defaults to true. Not used with the ssh format.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathKeyByFingerprintRead,
+			},
+		},
+		HelpSynopsis:    pathKeyByFingerprintHelpSyn,
+		HelpDescription: pathKeyByFingerprintHelpDesc,
+	}
+}
+
 func pathKeys(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "keys/" + framework.GenericNameRegex("name"),
+		Pattern: "keys/" + keyNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
 				Type:        framework.TypeString,
 				Description: "Name of the key.",
 			},
+			"export_format": {
+				Type:        framework.TypeString,
+				Default:     "armored",
+				Description: `The format of the returned public key. Can be "armored", "base64" for the binary key encoded in base64 or "ssh" for the OpenSSH public key of the authentication key. Defaults to "armored".`,
+			},
+			"line_ending": {
+				Type:        framework.TypeString,
+				Default:     "lf",
+				Description: `The line ending of the armored public key. Can be "lf" or "crlf". Defaults to "lf".`,
+			},
+			"include_subkeys": {
+				Type:        framework.TypeBool,
+				Default:     true,
+				Description: "Includes the subkeys in the returned public key. Defaults to true. Not used with the ssh format.",
+			},
 			"real_name": {
 				Type:        framework.TypeString,
-				Description: "The real name of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				Description: "The real name of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is true.",
 			},
 			"email": {
 				Type:        framework.TypeString,
-				Description: "The email of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				Description: "The email of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is true.",
 			},
 			"comment": {
 				Type:        framework.TypeString,
-				Description: "The comment of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is false.",
+				Description: "The comment of the identity associated with the generated GPG key. Must not contain any of \"()<>\x00\". Only used if generate is true.",
+			},
+			"identities": {
+				Type:        framework.TypeSlice,
+				Description: "A list of identities, each with a real_name, an email and a comment, associated with the generated GPG key. The first identity is the primary one. Cannot be used with real_name, email and comment. Only used if generate is true.",
+			},
+			"no_encryption_subkey": {
+				Type:        framework.TypeBool,
+				Description: "Generates the GPG key without an encryption subkey, for keys only used to sign. Only used if generate is true.",
+			},
+			"subkeys": {
+				Type:        framework.TypeSlice,
+				Description: `A list of subkeys generated instead of the encryption subkey, each with a usage ("encrypt" or "sign"), an algorithm defaulting to the algorithm of the primary key, a curve and a key_bits. Only used if generate is true.`,
+			},
+			"primary_flags": {
+				Type:        framework.TypeCommaStringSlice,
+				Default:     []string{"certify", "sign"},
+				Description: `The capabilities of the primary key of the generated GPG key. Can contain "certify", "sign" and "authenticate", "certify" is required. Defaults to "certify" and "sign". Only used if generate is true.`,
+			},
+			"algorithm": {
+				Type:        framework.TypeString,
+				Default:     "rsa",
+				Description: `The public key algorithm of the generated GPG key. Can be "rsa", "ecdsa" or "eddsa". Defaults to "rsa". Only used if generate is true.`,
+			},
+			"curve": {
+				Type:        framework.TypeString,
+				Description: `The elliptic curve of the generated GPG key. Can be "nistp256", "nistp384" or "nistp521" with the ecdsa algorithm and "curve25519" with the eddsa algorithm. Defaults to "nistp256" for ecdsa and "curve25519" for eddsa. Only used if generate is true.`,
 			},
 			"key_bits": {
 				Type:        framework.TypeInt,
-				Default:     2048,
-				Description: "The number of bits to use. Only used if generate is true.",
+				Description: "The number of bits to use. Defaults to the default_rsa_bits configuration. Only used if generate is true and algorithm is rsa.",
+			},
+			"key_expires": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The validity period of the generated GPG key, either as a duration string or as a number of seconds. A zero value means the key never expires. Only used if generate is true.",
+			},
+			"preferred_ciphers": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `The symmetric ciphers advertised as preferred by the generated GPG key, most preferred first. Can contain "aes128", "aes192" and "aes256". Only used if generate is true.`,
+			},
+			"preferred_hashes": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `The hash algorithms advertised as preferred by the generated GPG key, most preferred first. Can contain "sha2-224", "sha2-256", "sha2-384" and "sha2-512". Only used if generate is true.`,
+			},
+			"preferred_compression": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `The compression algorithms advertised as preferred by the generated GPG key, most preferred first. Can contain "none", "zip" and "zlib". Only used if generate is true.`,
+			},
+			"seed": {
+				Type:        framework.TypeString,
+				Description: "The hex-encoded seed used to deterministically generate the GPG key. Unsafe, must only be used for testing purposes. Requires allow_seeded_keys to be enabled in the configuration and the eddsa algorithm. Only used if generate is true.",
+			},
+			"aead": {
+				Type:        framework.TypeBool,
+				Description: "Advertises the support of AEAD (SEIPD v2) in the self-signature of the GPG key, so the messages encrypted for it can use AEAD. Only used if generate is true.",
+			},
+			"creation_time": {
+				Type:        framework.TypeString,
+				Description: "The RFC3339 creation time of the generated GPG key and of its subkeys. If empty, the current time is used, or the Unix epoch for seeded keys. Only used if generate is true.",
 			},
 			"key": {
 				Type:        framework.TypeString,
-				Description: "The ASCII-armored GPG key to use. Only used if generate is false.",
+				Description: "The ASCII-armored GPG key to use, or the base64-encoded binary key if input_format is binary. Only used if generate is false.",
+			},
+			"input_format": {
+				Type:        framework.TypeString,
+				Default:     "ascii-armor",
+				Description: `The format of the key to import. Can be "ascii-armor" or "binary" for a base64-encoded binary key. Defaults to "ascii-armor". Only used if generate is false and key is set.`,
+			},
+			"key_source": {
+				Type:        framework.TypeString,
+				Default:     "vault",
+				Description: `Where the private key is stored. Can be "vault" or "pkcs11" for a key stored in a PKCS#11 token, such as an HSM, whose public key is imported. Defaults to "vault".`,
+			},
+			"pkcs11_token_label": {
+				Type:        framework.TypeString,
+				Description: "The label of the PKCS#11 token storing the private key. Only used if key_source is pkcs11.",
+			},
+			"pkcs11_key_label": {
+				Type:        framework.TypeString,
+				Description: "The label of the private key in the PKCS#11 token. Only used if key_source is pkcs11.",
+			},
+			"keyserver_url": {
+				Type:        framework.TypeString,
+				Description: "The URL of the HKPS keyserver to fetch the public key from. Requires allow_keyserver_import to be enabled in the configuration. Only used if generate is false and key is not set.",
+			},
+			"fingerprint": {
+				Type:        framework.TypeString,
+				Description: "The fingerprint of the public key to fetch from the keyserver. Only used if keyserver_url is set.",
+			},
+			"select_fingerprint": {
+				Type:        framework.TypeString,
+				Description: "The fingerprint of the key to import when the ASCII-armored key contains several keys. The fingerprint of the primary key or of one of its subkeys can be used. Only used if generate is false and key is set.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase used to encrypt the private key before it is stored. When set, the passphrase must be provided to use the private key.",
+			},
+			"subkey_flags": {
+				Type:        framework.TypeKVPairs,
+				Description: `Replaces the capabilities of subkeys of the imported private key, mapping the hex-encoded fingerprint of each subkey to a comma-separated list of "sign", "encrypt" and "authenticate". The binding signatures of these subkeys are made again with the primary key. Only used if generate is false and key is set.`,
+			},
+			"import_passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase protecting the ASCII-armored private key, used to decrypt it before it is stored. Only used if generate is false and key is set.",
 			},
 			"exportable": {
 				Type:        framework.TypeBool,
 				Description: "Enables the key to be exportable.",
 			},
+			"tags": {
+				Type:        framework.TypeKVPairs,
+				Description: "Arbitrary key-value tags used to organize the keys. Tag keys must not be empty or contain \":\".",
+			},
+			"usage_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period after which Vault refuses to sign and decrypt with the key, either as a duration string or as a number of seconds. It is independent from the validity period of the GPG key. A zero value means the key is usable forever.",
+			},
+			"max_operations_per_second": {
+				Type:        framework.TypeInt,
+				Description: "The maximum number of sign and decrypt operations per second allowed with the key on each Vault node. A zero value means no limit.",
+			},
 			"generate": {
 				Type:        framework.TypeBool,
 				Default:     true,
 				Description: "Determines if a key should be generated by Vault or if a key is being passed from another service.",
 			},
+			"force": {
+				Type:        framework.TypeBool,
+				Description: "Allows to overwrite an existing key. The previous key material is lost.",
+			},
+			"allow_public_only": {
+				Type:        framework.TypeBool,
+				Description: "Allows to import a key without a private key. Such a key can only be used to encrypt data and verify signatures. Only used if generate is false.",
+			},
+			"confirm": {
+				Type:        framework.TypeBool,
+				Description: "Confirms the deletion of the key. Required to delete a key if require_delete_confirmation is enabled in the configuration.",
+			},
+			"preview": {
+				Type:        framework.TypeBool,
+				Description: "If true, the fingerprint and the public key of the key are returned without storing it.",
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -82,6 +308,12 @@ func pathKeys(b *backend) *framework.Path {
 	}
 }
 
+// keyNotFound returns the error of a request on the named key when it does not exist.
+func keyNotFound(name string) (*logical.Response, error) {
+	message := fmt.Sprintf("key not found: %s", name)
+	return logical.ErrorResponse(message), logical.CodedError(http.StatusNotFound, message)
+}
+
 func (b *backend) key(ctx context.Context, s logical.Storage, name string) (*keyEntry, error) {
 	entry, err := s.Get(ctx, "key/"+name)
 	if err != nil {
@@ -91,24 +323,249 @@ func (b *backend) key(ctx context.Context, s logical.Storage, name string) (*key
 		return nil, nil
 	}
 
-	var result keyEntry
+	// Keys stored before they could be disabled are enabled.
+	result := keyEntry{Enabled: true}
 	if err := entry.DecodeJSON(&result); err != nil {
 		return nil, err
 	}
 
+	// Keys stored before their metadata were recorded get them from the key material. The entry is only migrated in
+	// memory, a read racing with a write could otherwise overwrite the newer entry; the metadata are persisted by the
+	// next write of the key. Entries whose key material cannot be parsed are returned as is, the operations using
+	// them report the error.
+	if result.Algorithm == "" {
+		entity, err := b.entity(&result)
+		if err != nil {
+			return &result, nil
+		}
+		if err = result.setMetadata(entity); err != nil {
+			return &result, nil
+		}
+	}
+
 	return &result, nil
 }
 
+// keyNames returns the names of the keys of the folder and of its subfolders, relative to the folder.
+func (b *backend) keyNames(ctx context.Context, s logical.Storage, folder string) ([]string, error) {
+	names, err := logical.CollectKeys(ctx, logical.NewStorageView(s, "key/"+folder))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func (b *backend) entity(entry *keyEntry) (*openpgp.Entity, error) {
 	r := bytes.NewReader(entry.SerializedKey)
 	el, err := openpgp.ReadKeyRing(r)
 	if err != nil {
 		return nil, err
 	}
+	b.attachPKCS11Key(entry, el[0])
 
 	return el[0], nil
 }
 
+// keyring returns the entities of all the versions of a key, the latest one first.
+func (b *backend) keyring(entry *keyEntry) (openpgp.EntityList, error) {
+	keyring, err := openpgp.ReadKeyRing(bytes.NewReader(entry.SerializedKey))
+	if err != nil {
+		return nil, err
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("no key found")
+	}
+	b.attachPKCS11Key(entry, keyring[0])
+	for _, previousKey := range entry.PreviousKeys {
+		el, err := openpgp.ReadKeyRing(bytes.NewReader(previousKey))
+		if err != nil {
+			return nil, err
+		}
+		if len(el) == 0 {
+			return nil, fmt.Errorf("no previous key found")
+		}
+		keyring = append(keyring, el[0])
+	}
+	return keyring, nil
+}
+
+// canDecrypt reports whether the entity holds a private key usable to decrypt messages.
+// Keys whose self-signature does not declare any flags are assumed to be usable.
+func canDecrypt(entity *openpgp.Entity) bool {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if entity.PrivateKey != nil && hasEncryptionFlags(selfSignature) {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && hasEncryptionFlags(subkey.Sig) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEncryptionFlags(sig *packet.Signature) bool {
+	return sig != nil && (!sig.FlagsValid || sig.FlagEncryptCommunications || sig.FlagEncryptStorage)
+}
+
+// canSign reports whether the primary key or a subkey of the entity is signing capable, regardless of its
+// validity. Primary keys whose self-signature does not declare any flags are assumed to be signing capable.
+func canSign(entity *openpgp.Entity) bool {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if entity.PrimaryKey.PubKeyAlgo.CanSign() && selfSignature != nil && (!selfSignature.FlagsValid || selfSignature.FlagSign) {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PublicKey.PubKeyAlgo.CanSign() && subkey.Sig.FlagsValid && subkey.Sig.FlagSign {
+			return true
+		}
+	}
+	return false
+}
+
+// canEncrypt reports whether the primary key or a subkey of the entity is encryption capable, regardless of
+// its validity.
+func canEncrypt(entity *openpgp.Entity) bool {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if entity.PrimaryKey.PubKeyAlgo.CanEncrypt() && hasEncryptionFlags(selfSignature) {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PublicKey.PubKeyAlgo.CanEncrypt() && subkey.Sig.FlagsValid && hasEncryptionFlags(subkey.Sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// decryptPrivateKeys decrypts in memory the private keys of an entity protected by a passphrase.
+func decryptPrivateKeys(entity *openpgp.Entity, passphrase string) error {
+	if entity.PrivateKey == nil {
+		return fmt.Errorf("the key does not have a private key")
+	}
+	if !privateKeysEncrypted(entity) {
+		return nil
+	}
+	if passphrase == "" {
+		return fmt.Errorf("the key is protected by a passphrase, a passphrase is required")
+	}
+	if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+		return fmt.Errorf("unable to decrypt the key with the given passphrase: %s", err)
+	}
+	return nil
+}
+
+// decryptKeyringPrivateKeys decrypts in memory the private keys of the versions of a keyring protected by a
+// passphrase. The versions the passphrase does not unlock, when the key was rotated with another passphrase, are
+// skipped; it only fails if no version can be used.
+func decryptKeyringPrivateKeys(keyring openpgp.EntityList, passphrase string) error {
+	var firstErr error
+	unlocked := false
+	for _, entity := range keyring {
+		if err := decryptPrivateKeys(entity, passphrase); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		unlocked = true
+	}
+	if !unlocked {
+		return firstErr
+	}
+	return nil
+}
+
+// privateKeysEncrypted reports whether the private key or a private subkey of the entity is protected by a passphrase.
+func privateKeysEncrypted(entity *openpgp.Entity) bool {
+	encrypted := entity.PrivateKey != nil && entity.PrivateKey.Encrypted
+	for _, subkey := range entity.Subkeys {
+		encrypted = encrypted || (subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted)
+	}
+	return encrypted
+}
+
+// serializePublicKey writes the entity as a transferable public key in the order of RFC 4880 section 11.1 so every
+// version of gpg imports it: the primary key with its revocations and direct signatures, the identities, the primary
+// one first and the others sorted, each followed by its self-signature and its other signatures, then the subkeys if
+// they are included, each followed by its binding signature and its revocations.
+func serializePublicKey(w io.Writer, entity *openpgp.Entity, includeSubkeys bool) error {
+	if err := entity.PrimaryKey.Serialize(w); err != nil {
+		return err
+	}
+	for _, revocation := range entity.Revocations {
+		if err := revocation.Serialize(w); err != nil {
+			return err
+		}
+	}
+	for _, directSignature := range entity.Signatures {
+		if err := directSignature.Serialize(w); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range orderedIdentities(entity) {
+		if err := id.UserId.Serialize(w); err != nil {
+			return err
+		}
+		if id.SelfSignature != nil {
+			if err := id.SelfSignature.Serialize(w); err != nil {
+				return err
+			}
+		}
+		for _, sig := range id.Signatures {
+			if sig == id.SelfSignature {
+				continue
+			}
+			if err := sig.Serialize(w); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !includeSubkeys {
+		return nil
+	}
+	for _, subkey := range entity.Subkeys {
+		if err := subkey.PublicKey.Serialize(w); err != nil {
+			return err
+		}
+		if err := subkey.Sig.Serialize(w); err != nil {
+			return err
+		}
+		for _, revocation := range subkey.Revocations {
+			if err := revocation.Serialize(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// orderedIdentities returns all the identities of the entity, the primary one first and the others sorted by name,
+// so the serialized keys do not depend on the order of the map.
+func orderedIdentities(entity *openpgp.Entity) []*openpgp.Identity {
+	primary := entity.PrimaryIdentity()
+	names := make([]string, 0, len(entity.Identities))
+	for name, id := range entity.Identities {
+		if id != primary {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	identities := make([]*openpgp.Identity, 0, len(entity.Identities))
+	if primary != nil {
+		identities = append(identities, primary)
+	}
+	for _, name := range names {
+		identities = append(identities, entity.Identities[name])
+	}
+	return identities
+}
+
+// serializePrivateWithoutSigning writes the entity with its private keys in the same order as serializePublicKey,
+// keeping all the signatures as they are so the revocations and the third-party certifications are not lost.
 func serializePrivateWithoutSigning(w io.Writer, e *openpgp.Entity) (err error) {
 	foundPrivateKey := false
 
@@ -119,7 +576,20 @@ func serializePrivateWithoutSigning(w io.Writer, e *openpgp.Entity) (err error)
 			return
 		}
 	}
-	for _, ident := range e.Identities {
+	for _, revocation := range e.Revocations {
+		err = revocation.Serialize(w)
+		if err != nil {
+			return
+		}
+	}
+	// The direct-key signatures carry the properties of V6 keys
+	for _, directSignature := range e.Signatures {
+		err = directSignature.Serialize(w)
+		if err != nil {
+			return
+		}
+	}
+	for _, ident := range orderedIdentities(e) {
 		err = ident.UserId.Serialize(w)
 		if err != nil {
 			return
@@ -128,6 +598,16 @@ func serializePrivateWithoutSigning(w io.Writer, e *openpgp.Entity) (err error)
 		if err != nil {
 			return
 		}
+		// The other signatures include the revocations of the identity and its third-party certifications
+		for _, sig := range ident.Signatures {
+			if sig == ident.SelfSignature {
+				continue
+			}
+			err = sig.Serialize(w)
+			if err != nil {
+				return
+			}
+		}
 	}
 	for _, subkey := range e.Subkeys {
 		if subkey.PrivateKey != nil {
@@ -137,10 +617,17 @@ func serializePrivateWithoutSigning(w io.Writer, e *openpgp.Entity) (err error)
 				return
 			}
 		}
+		// The binding signature comes before the revocations as required by RFC 4880 section 11.1
 		err = subkey.Sig.Serialize(w)
 		if err != nil {
 			return
 		}
+		for _, revocation := range subkey.Revocations {
+			err = revocation.Serialize(w)
+			if err != nil {
+				return
+			}
+		}
 	}
 
 	if !foundPrivateKey {
@@ -151,109 +638,1461 @@ func serializePrivateWithoutSigning(w io.Writer, e *openpgp.Entity) (err error)
 }
 
 func (b *backend) pathKeyRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	name := data.Get("name").(string)
+	entry, err := b.key(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
 	if entry == nil {
-		return nil, nil
+		return keyNotFound(name)
 	}
-	entity, err := b.entity(entry)
-	if err != nil {
+	resp, err := b.keyResponse(name, entry, data.Get("export_format").(string), data.Get("line_ending").(string), data.Get("include_subkeys").(bool))
+	if err != nil || resp.IsError() {
+		return resp, err
+	}
+	if err = b.addKeyUsage(ctx, req.Storage, name, resp); err != nil {
 		return nil, err
 	}
+	return resp, nil
+}
 
-	var buf bytes.Buffer
-	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
-	err = entity.Serialize(w)
-	w.Close()
+func (b *backend) pathKeyByFingerprintRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	fingerprint := data.Get("fingerprint").(string)
+	exportFormat := data.Get("export_format").(string)
+	lineEnding := data.Get("line_ending").(string)
+	// The input is checked before the stored keys are scanned
+	if _, err := hex.DecodeString(fingerprint); err != nil || (len(fingerprint) != 40 && len(fingerprint) != 64) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid fingerprint %s; must be the 40 or 64 hex characters of a fingerprint", fingerprint)), logical.ErrInvalidRequest
+	}
+	if err := checkExportFormat(exportFormat); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err := checkLineEnding(lineEnding); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	names, err := b.keyNames(ctx, req.Storage, "")
 	if err != nil {
 		return nil, err
 	}
-
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
-			"public_key":  buf.String(),
-			"exportable":  entry.Exportable,
-		},
-	}, nil
-}
-
-func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	name := data.Get("name").(string)
-	realName := data.Get("real_name").(string)
-	email := data.Get("email").(string)
-	comment := data.Get("comment").(string)
-	keyBits := data.Get("key_bits").(int)
-	exportable := data.Get("exportable").(bool)
-	generate := data.Get("generate").(bool)
-	key := data.Get("key").(string)
-
-	var buf bytes.Buffer
-	switch generate {
-	case true:
-		if keyBits < 2048 {
-			return logical.ErrorResponse("Keys < 2048 bits are unsafe and not supported"), nil
+	for _, name := range names {
+		entry, err := b.key(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
 		}
-		config := packet.Config{
-			RSABits: keyBits,
+		if entry == nil {
+			continue
 		}
-		entity, err := openpgp.NewEntity(realName, comment, email, &config)
+		keyring, err := b.keyring(entry)
 		if err != nil {
 			return nil, err
 		}
-		err = entity.SerializePrivate(&buf, nil)
-		if err != nil {
+		if !hasFingerprint(keyring, fingerprint) {
+			continue
+		}
+		resp, err := b.keyResponse(name, entry, exportFormat, lineEnding, data.Get("include_subkeys").(bool))
+		if err != nil || resp.IsError() {
+			return resp, err
+		}
+		if err = b.addKeyUsage(ctx, req.Storage, name, resp); err != nil {
 			return nil, err
 		}
-	default:
-		if key == "" {
-			return logical.ErrorResponse("the key value is required for generated keys"), nil
+		resp.Data["name"] = name
+		return resp, nil
+	}
+	message := fmt.Sprintf("no key found with the fingerprint: %s", fingerprint)
+	return logical.ErrorResponse(message), logical.CodedError(http.StatusNotFound, message)
+}
+
+// hasFingerprint reports whether a primary key or a subkey of the keyring matches the hex-encoded fingerprint.
+func hasFingerprint(keyring openpgp.EntityList, fingerprint string) bool {
+	for _, entity := range keyring {
+		if strings.EqualFold(hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]), fingerprint) {
+			return true
+		}
+		if _, ok := findSubkey(entity, fingerprint); ok {
+			return true
 		}
+	}
+	return false
+}
+
+// readKeyRing reads the keys to import encoded in the given format.
+func readKeyRing(key string, inputFormat string) (openpgp.EntityList, error) {
+	switch inputFormat {
+	case "ascii-armor":
 		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
 		if err != nil {
-			return logical.ErrorResponse(err.Error()), nil
+			return nil, armorError("key", err)
 		}
-		err = serializePrivateWithoutSigning(&buf, el[0])
+		return el, nil
+	case "binary":
+		decoded, err := base64.StdEncoding.DecodeString(key)
 		if err != nil {
-			return logical.ErrorResponse("the key could not be serialized, is a private key present?"), nil
+			return nil, fmt.Errorf("unable to decode the binary key as base64: %s", err)
 		}
+		return openpgp.ReadKeyRing(bytes.NewReader(decoded))
+	default:
+		return nil, fmt.Errorf("unsupported input format %s; must be \"ascii-armor\" or \"binary\"", inputFormat)
 	}
+}
 
-	entry, err := logical.StorageEntryJSON("key/"+name, &keyEntry{
-		SerializedKey: buf.Bytes(),
-		Exportable:    exportable,
-	})
-	if err != nil {
-		return nil, err
-	}
-	if err := req.Storage.Put(ctx, entry); err != nil {
-		return nil, err
-	}
-	return nil, nil
+// armorError explains that an armored block, usually truncated or corrupted when pasted, cannot be parsed.
+func armorError(what string, err error) error {
+	return fmt.Errorf("failed to parse armored %s: %s; ensure you pasted the complete -----BEGIN/END----- block", what, err)
 }
 
-func (b *backend) pathKeyDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete(ctx, "key/"+data.Get("name").(string))
-	if err != nil {
-		return nil, err
+// selectEntity returns the entity of the keyring matching the hex-encoded fingerprint, or its only entity when
+// the fingerprint is empty.
+func selectEntity(el openpgp.EntityList, fingerprint string) (*openpgp.Entity, error) {
+	if fingerprint == "" {
+		if len(el) > 1 {
+			return nil, fmt.Errorf("the key contains %d keys, select_fingerprint must be set to choose the key to import", len(el))
+		}
+		return el[0], nil
+	}
+	fingerprint = strings.ToLower(strings.Replace(fingerprint, " ", "", -1))
+	for _, entity := range el {
+		if hasFingerprint(openpgp.EntityList{entity}, fingerprint) {
+			return entity, nil
+		}
 	}
-	return nil, nil
+	return nil, fmt.Errorf("no key matching the fingerprint %s", fingerprint)
+}
+
+// keyResponse returns the public information about the named key, the public key being encoded in the export format.
+func (b *backend) keyResponse(name string, entry *keyEntry, exportFormat string, lineEnding string, includeSubkeys bool) (*logical.Response, error) {
+	if err := checkExportFormat(exportFormat); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err := checkLineEnding(lineEnding); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	var publicKey string
+	switch exportFormat {
+	case "ssh":
+		publicKey, err = sshPublicKey(entity)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	case "base64":
+		if err = serializePublicKey(&buf, entity, includeSubkeys); err != nil {
+			return nil, err
+		}
+		publicKey = base64.StdEncoding.EncodeToString(buf.Bytes())
+	default:
+		w, err := armor.Encode(&buf, openpgp.PublicKeyType, keyArmorHeaders(name))
+		if err != nil {
+			return nil, err
+		}
+		if err = serializePublicKey(w, entity, includeSubkeys); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+		publicKey = withLineEnding(buf.String(), lineEnding)
+	}
+
+	keyring, err := b.keyring(entry)
+	if err != nil {
+		return nil, err
+	}
+	previousFingerprints := []string{}
+	for _, previous := range keyring[1:] {
+		previousFingerprints = append(previousFingerprints, hex.EncodeToString(previous.PrimaryKey.Fingerprint[:]))
+	}
+
+	subkeys := []map[string]interface{}{}
+	for _, subkey := range entity.Subkeys {
+		var subkeyExpiresAt interface{}
+		if subkey.Sig.KeyLifetimeSecs != nil && *subkey.Sig.KeyLifetimeSecs != 0 {
+			subkeyExpiresAt = subkey.PublicKey.CreationTime.Add(time.Duration(*subkey.Sig.KeyLifetimeSecs) * time.Second).UTC().Format(time.RFC3339)
+		}
+		subkeys = append(subkeys, map[string]interface{}{
+			"fingerprint":   hex.EncodeToString(subkey.PublicKey.Fingerprint[:]),
+			"creation_time": subkey.PublicKey.CreationTime.UTC().Format(time.RFC3339),
+			"expires_at":    subkeyExpiresAt,
+			"capabilities":  keyCapabilities(subkey.Sig),
+			"algorithm":     publicKeyAlgorithmName(subkey.PublicKey.PubKeyAlgo),
+			"strength":      keyStrength(subkey.PublicKey),
+		})
+	}
+
+	identities := []map[string]interface{}{}
+	for _, id := range entityIdentities(entity) {
+		identities = append(identities, map[string]interface{}{
+			"real_name": id.UserId.Name,
+			"email":     id.UserId.Email,
+			"comment":   id.UserId.Comment,
+		})
+	}
+
+	selfSignature, _ := entity.PrimarySelfSignature()
+
+	var expiresAt, expiresUnix interface{}
+	if expiration, ok := keyExpiration(entity); ok {
+		expiresAt = expiration.UTC().Format(time.RFC3339)
+		expiresUnix = expiration.Unix()
+	}
+	var usableUntil interface{}
+	if !entry.UsableUntil.IsZero() {
+		usableUntil = entry.UsableUntil.UTC().Format(time.RFC3339)
+	}
+	// The subkey the encrypt operation uses, it is the most recent valid one.
+	var encryptionSubkeyFingerprint interface{}
+	if encryptionKey, ok := entity.EncryptionKey(time.Now()); ok && encryptionKey.PublicKey != entity.PrimaryKey {
+		encryptionSubkeyFingerprint = hex.EncodeToString(encryptionKey.PublicKey.Fingerprint)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"fingerprint":                   hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
+			"formatted_fingerprint":         formatFingerprint(entity.PrimaryKey.Fingerprint),
+			"key_id":                        fmt.Sprintf("%016x", entity.PrimaryKey.KeyId),
+			"short_key_id":                  fmt.Sprintf("%08x", uint32(entity.PrimaryKey.KeyId)),
+			"has_private_key":               entity.PrivateKey != nil,
+			"public_key":                    publicKey,
+			"exportable":                    entry.Exportable,
+			"enabled":                       entry.Enabled,
+			"expires_at":                    expiresAt,
+			"expires_unix":                  expiresUnix,
+			"created_unix":                  entity.PrimaryKey.CreationTime.Unix(),
+			"version":                       entity.PrimaryKey.Version,
+			"previous_fingerprints":         previousFingerprints,
+			"creation_time":                 entry.CreationTime.UTC().Format(time.RFC3339),
+			"algorithm":                     entry.Algorithm,
+			"key_bits":                      entry.KeyBits,
+			"strength":                      keyStrength(entity.PrimaryKey),
+			"subkeys":                       subkeys,
+			"encryption_subkey_fingerprint": encryptionSubkeyFingerprint,
+			"capabilities":                  keyCapabilities(selfSignature),
+			"usable_until":                  usableUntil,
+			"identities":                    identities,
+			"tags":                          entry.tags(),
+			"max_operations_per_second":     entry.MaxOperationsPerSecond,
+			"key_source":                    entry.keySource(),
+		},
+	}, nil
+}
+
+// keyArmorHeaders returns the armor headers describing the named key.
+func keyArmorHeaders(name string) map[string]string {
+	return map[string]string{
+		"Comment": "Vault key " + name,
+	}
+}
+
+// checkExportFormat returns an error if the public keys cannot be returned in the export format.
+func checkExportFormat(exportFormat string) error {
+	switch exportFormat {
+	case "armored", "base64", "ssh":
+		return nil
+	default:
+		return fmt.Errorf("unsupported export format %s; must be \"armored\", \"base64\" or \"ssh\"", exportFormat)
+	}
+}
+
+// checkLineEnding returns an error if the line ending is not supported.
+func checkLineEnding(lineEnding string) error {
+	switch lineEnding {
+	case "lf", "crlf":
+		return nil
+	default:
+		return fmt.Errorf("unsupported line ending %s; must be \"lf\" or \"crlf\"", lineEnding)
+	}
+}
+
+// withLineEnding returns the armored text using the given line ending.
+func withLineEnding(armored string, lineEnding string) string {
+	if lineEnding == "crlf" {
+		return strings.Replace(armored, "\n", "\r\n", -1)
+	}
+	return armored
+}
+
+// formatFingerprint returns the fingerprint the way GnuPG prints it, in uppercase groups of four characters with
+// a double space in the middle.
+func formatFingerprint(fingerprint []byte) string {
+	encoded := strings.ToUpper(hex.EncodeToString(fingerprint))
+	var formatted strings.Builder
+	for i := 0; i < len(encoded); i += 4 {
+		switch {
+		case i == len(encoded)/2:
+			formatted.WriteString("  ")
+		case i > 0:
+			formatted.WriteString(" ")
+		}
+		formatted.WriteString(encoded[i:min(i+4, len(encoded))])
+	}
+	return formatted.String()
+}
+
+// findSubkey returns the subkey of the entity matching the hex-encoded fingerprint.
+func findSubkey(entity *openpgp.Entity, fingerprint string) (*openpgp.Subkey, bool) {
+	for i, subkey := range entity.Subkeys {
+		if strings.EqualFold(hex.EncodeToString(subkey.PublicKey.Fingerprint[:]), fingerprint) {
+			return &entity.Subkeys[i], true
+		}
+	}
+	return nil, false
+}
+
+// keyCapabilities returns the usages declared by the flags of a key signature.
+func keyCapabilities(sig *packet.Signature) []string {
+	capabilities := []string{}
+	if sig == nil || !sig.FlagsValid {
+		return capabilities
+	}
+	if sig.FlagCertify {
+		capabilities = append(capabilities, "certify")
+	}
+	if sig.FlagSign {
+		capabilities = append(capabilities, "sign")
+	}
+	if sig.FlagEncryptCommunications || sig.FlagEncryptStorage {
+		capabilities = append(capabilities, "encrypt")
+	}
+	if sig.FlagAuthenticate {
+		capabilities = append(capabilities, "authenticate")
+	}
+	return capabilities
+}
+
+func keyExpiration(entity *openpgp.Entity) (time.Time, bool) {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if selfSignature == nil || selfSignature.KeyLifetimeSecs == nil || *selfSignature.KeyLifetimeSecs == 0 {
+		return time.Time{}, false
+	}
+	return entity.PrimaryKey.CreationTime.Add(time.Duration(*selfSignature.KeyLifetimeSecs) * time.Second), true
+}
+
+// checkExpiration returns an error if the entity cannot sign, or encrypt, at the given time
+// because the key or all of the subkeys with that capability expired.
+func checkExpiration(entity *openpgp.Entity, now time.Time, signing bool) error {
+	if expiration, ok := keyExpiration(entity); ok && now.After(expiration) {
+		return fmt.Errorf("key expired on %s", expiration.UTC().Format(time.RFC3339))
+	}
+	if signing {
+		if _, ok := entity.SigningKey(now); ok {
+			return nil
+		}
+	} else if _, ok := entity.EncryptionKey(now); ok {
+		return nil
+	}
+	for i := len(entity.Subkeys) - 1; i >= 0; i-- {
+		subkey := entity.Subkeys[i]
+		capable := subkey.Sig.FlagSign
+		if !signing {
+			capable = subkey.Sig.FlagEncryptCommunications || subkey.Sig.FlagEncryptStorage
+		}
+		if capable && subkey.PublicKey.KeyExpired(subkey.Sig, now) && subkey.Sig.KeyLifetimeSecs != nil {
+			expiration := subkey.PublicKey.CreationTime.Add(time.Duration(*subkey.Sig.KeyLifetimeSecs) * time.Second)
+			return fmt.Errorf("subkey %s expired on %s", hex.EncodeToString(subkey.PublicKey.Fingerprint[:]), expiration.UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// ignoreExpiration removes the expiration of the key and its subkeys from the in-memory entity so
+// it can still be used once expired. The stored key is not modified.
+func ignoreExpiration(entity *openpgp.Entity) {
+	for _, id := range entity.Identities {
+		if id.SelfSignature != nil {
+			id.SelfSignature.KeyLifetimeSecs = nil
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		subkey.Sig.KeyLifetimeSecs = nil
+	}
+}
+
+func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	realName := data.Get("real_name").(string)
+	email := data.Get("email").(string)
+	comment := data.Get("comment").(string)
+	algorithm := data.Get("algorithm").(string)
+	keyExpires := data.Get("key_expires").(int)
+	exportable := data.Get("exportable").(bool)
+	usageTTL := data.Get("usage_ttl").(int)
+	maxOperationsPerSecond := data.Get("max_operations_per_second").(int)
+	tags := data.Get("tags").(map[string]string)
+	generate := data.Get("generate").(bool)
+	key := data.Get("key").(string)
+	keyserverURL := data.Get("keyserver_url").(string)
+	passphrase := data.Get("passphrase").(string)
+	subkeyFlags := data.Get("subkey_flags").(map[string]string)
+
+	if maxOperationsPerSecond < 0 {
+		return logical.ErrorResponse(fmt.Sprintf("invalid max_operations_per_second %d; must not be negative", maxOperationsPerSecond)), nil
+	}
+
+	// A preview does not store anything, it does not depend on an existing key with the same name
+	preview := data.Get("preview").(bool)
+	var existing *keyEntry
+	var err error
+	if !preview {
+		existing, err = b.key(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if existing != nil {
+		// An imported key is compared with the existing one once it is read
+		if generate && !data.Get("force").(bool) {
+			return logical.ErrorResponse(fmt.Sprintf("key %s already exists; set force to true to overwrite it", name)), nil
+		}
+		if !existing.Exportable && exportable {
+			return logical.ErrorResponse(fmt.Sprintf("key %s is not exportable and cannot be made exportable", name)), nil
+		}
+	}
+	if err = validateTags(tags); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	keySource := data.Get("key_source").(string)
+	switch keySource {
+	case "vault":
+	case keySourcePKCS11:
+		if generate || keyserverURL != "" {
+			return logical.ErrorResponse("the public key of a key stored in a PKCS#11 token must be imported, generate must be false"), nil
+		}
+		if exportable {
+			return logical.ErrorResponse("a key stored in a PKCS#11 token cannot be exportable"), nil
+		}
+		if passphrase != "" {
+			return logical.ErrorResponse("a passphrase cannot be used with a key stored in a PKCS#11 token"), nil
+		}
+		if data.Get("pkcs11_token_label").(string) == "" || data.Get("pkcs11_key_label").(string) == "" {
+			return logical.ErrorResponse("pkcs11_token_label and pkcs11_key_label are required with the pkcs11 key source"), nil
+		}
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported key source %s; must be \"vault\" or \"pkcs11\"", keySource)), nil
+	}
+
+	policy, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if err = policy.checkName(name); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	keyBits := policy.rsaBits(data)
+
+	var buf bytes.Buffer
+	var entity *openpgp.Entity
+	switch {
+	case generate:
+		identities := []identity{{realName: realName, comment: comment, email: email}}
+		if rawIdentities := data.Get("identities").([]interface{}); len(rawIdentities) > 0 {
+			if realName != "" || comment != "" || email != "" {
+				return logical.ErrorResponse("identities cannot be used with real_name, email or comment"), nil
+			}
+			identities, err = parseIdentities(rawIdentities)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+		for _, id := range identities {
+			if err = validateIdentity(id.realName, id.comment, id.email); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+		primaryFlags := data.Get("primary_flags").([]string)
+		if err = validatePrimaryFlags(primaryFlags); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		config, err := keyConfig(algorithm, data.Get("curve").(string), keyBits, keyExpires)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		// AEAD support (SEIPD v2) is only advertised on request, older versions of GnuPG cannot read such messages
+		if data.Get("aead").(bool) {
+			config.AEADConfig = &packet.AEADConfig{}
+		}
+		if err = policy.check(algorithm, keyBits); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		preferences, err := parseKeyPreferences(data.Get("preferred_ciphers").([]string), data.Get("preferred_hashes").([]string), data.Get("preferred_compression").([]string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		var subkeys []subkeySpec
+		if rawSubkeys := data.Get("subkeys").([]interface{}); len(rawSubkeys) > 0 {
+			if data.Get("no_encryption_subkey").(bool) {
+				return logical.ErrorResponse("subkeys cannot be used with no_encryption_subkey"), nil
+			}
+			subkeys, err = parseSubkeySpecs(rawSubkeys, algorithm, policy, keyExpires)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+		if seed := data.Get("seed").(string); seed != "" {
+			if !policy.AllowSeededKeys {
+				return logical.ErrorResponse("seeded keys are not allowed by the allow_seeded_keys configuration"), nil
+			}
+			// RSA and ECDSA key generation are not deterministic in the Go standard library
+			if algorithm != "eddsa" {
+				return logical.ErrorResponse("seeded keys are only supported with the eddsa algorithm"), nil
+			}
+			for _, subkey := range subkeys {
+				if subkey.config.Algorithm != packet.PubKeyAlgoEdDSA {
+					return logical.ErrorResponse("seeded keys are only supported with eddsa subkeys"), nil
+				}
+			}
+			seedBytes, err := hex.DecodeString(seed)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("unable to decode seed as hex: %s", err)), nil
+			}
+			config.Rand = seededReader(seedBytes)
+			config.Time = func() time.Time {
+				return seededKeyCreationTime
+			}
+		}
+		if creationTime := data.Get("creation_time").(string); creationTime != "" {
+			t, err := time.Parse(time.RFC3339, creationTime)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid creation_time: %s", err)), nil
+			}
+			if t.Before(time.Unix(0, 0)) || t.After(time.Now()) {
+				return logical.ErrorResponse(fmt.Sprintf("invalid creation_time %s; must be between %s and now", creationTime, time.Unix(0, 0).UTC().Format(time.RFC3339))), nil
+			}
+			config.Time = func() time.Time {
+				return t
+			}
+		}
+		entity, err = generateEntityContext(ctx, identities, primaryFlags, preferences, config, subkeys)
+		if err != nil {
+			if ctx.Err() != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+			return nil, err
+		}
+		if data.Get("no_encryption_subkey").(bool) {
+			// The only subkey of a generated entity is its encryption subkey
+			entity.Subkeys = nil
+		}
+		if passphrase != "" {
+			err = entity.EncryptPrivateKeys([]byte(passphrase), nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = serializePrivateWithoutSigning(&buf, entity)
+		if err != nil {
+			return nil, err
+		}
+	case keyserverURL != "":
+		if !policy.AllowKeyserverImport {
+			return logical.ErrorResponse("importing keys from a keyserver is not allowed by the allow_keyserver_import configuration"), nil
+		}
+		if passphrase != "" {
+			return logical.ErrorResponse("a passphrase cannot be used with a public key imported from a keyserver"), nil
+		}
+		entity, err = b.fetchPublicKey(ctx, keyserverURL, data.Get("fingerprint").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if err = serializePublicKey(&buf, entity, true); err != nil {
+			return nil, err
+		}
+	default:
+		if key == "" {
+			return logical.ErrorResponse("the key value is required for generated keys"), nil
+		}
+		el, err := readKeyRing(key, data.Get("input_format").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		entity, err = selectEntity(el, data.Get("select_fingerprint").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if len(subkeyFlags) > 0 && entity.PrivateKey == nil {
+			return logical.ErrorResponse("subkey_flags requires the private key, the subkeys are bound again with the primary key"), nil
+		}
+		if keySource == keySourcePKCS11 {
+			if entity.PrivateKey != nil {
+				return logical.ErrorResponse("only the public key of a key stored in a PKCS#11 token must be imported"), nil
+			}
+			if _, err = b.pkcs11Signer(entity, data.Get("pkcs11_token_label").(string), data.Get("pkcs11_key_label").(string)); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+			if err = serializePublicKey(&buf, entity, true); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if entity.PrivateKey == nil && data.Get("allow_public_only").(bool) {
+			if passphrase != "" {
+				return logical.ErrorResponse("a passphrase cannot be used with a public key"), nil
+			}
+			if err = serializePublicKey(&buf, entity, true); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if privateKeysEncrypted(entity) {
+			importPassphrase := data.Get("import_passphrase").(string)
+			if importPassphrase == "" {
+				return logical.ErrorResponse("the private key is protected by a passphrase, import_passphrase is required to import it"), nil
+			}
+			if err = entity.DecryptPrivateKeys([]byte(importPassphrase)); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("unable to decrypt the private key with the import passphrase: %s", err)), nil
+			}
+		}
+		if err = applySubkeyFlags(entity, subkeyFlags); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if passphrase != "" {
+			err = entity.EncryptPrivateKeys([]byte(passphrase), nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = serializePrivateWithoutSigning(&buf, entity)
+		if err != nil {
+			return logical.ErrorResponse("the key could not be serialized, is a private key present? Set allow_public_only to true to import a public key"), nil
+		}
+	}
+
+	newEntry := &keyEntry{
+		SerializedKey:          buf.Bytes(),
+		Exportable:             exportable,
+		Tags:                   tags,
+		Enabled:                true,
+		MaxOperationsPerSecond: maxOperationsPerSecond,
+	}
+	if keySource == keySourcePKCS11 {
+		newEntry.KeySource = keySourcePKCS11
+		newEntry.PKCS11TokenLabel = data.Get("pkcs11_token_label").(string)
+		newEntry.PKCS11KeyLabel = data.Get("pkcs11_key_label").(string)
+	}
+	if usageTTL > 0 {
+		newEntry.UsableUntil = time.Now().Add(time.Duration(usageTTL) * time.Second)
+	}
+	if existing != nil && !generate && !data.Get("force").(bool) {
+		if !existing.sameImport(newEntry) {
+			return logical.ErrorResponse(fmt.Sprintf("key %s already exists; set force to true to overwrite it", name)), nil
+		}
+		// Importing the key already stored under the name with the same attributes is a no-op so the import can
+		// safely be repeated.
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
+				"no_change":   true,
+			},
+		}, nil
+	}
+	if err := newEntry.setMetadata(entity); err != nil {
+		return nil, err
+	}
+	// Weak imported keys are accepted, for example to verify the signatures of legacy keys, but reported.
+	var warnings []string
+	if generate {
+		if err := policy.check(newEntry.Algorithm, newEntry.KeyBits); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	} else {
+		warnings = policy.importWarnings(entity)
+	}
+	if preview {
+		// The public key is serialized as it is returned once the key is stored
+		var publicKey bytes.Buffer
+		w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, keyArmorHeaders(name))
+		if err != nil {
+			return nil, err
+		}
+		if err = serializePublicKey(w, entity, true); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
+				"public_key":  publicKey.String(),
+			},
+			Warnings: warnings,
+		}, nil
+	}
+	entry, err := logical.StorageEntryJSON("key/"+name, newEntry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	// The fingerprint confirms which key has been stored, in particular when it is imported.
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
+			"no_change":   false,
+		},
+		Warnings: warnings,
+	}, nil
+}
+
+// validateIdentity checks the fields of an identity can be used to build a well-formed user ID.
+func validateIdentity(realName, comment, email string) error {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"real_name", realName},
+		{"comment", comment},
+		{"email", email},
+	}
+	for _, field := range fields {
+		if strings.ContainsAny(field.value, "()<>\x00") {
+			return fmt.Errorf("invalid %s %q; must not contain any of \"()<>\\x00\"", field.name, field.value)
+		}
+	}
+	if email != "" {
+		parts := strings.Split(email, "@")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.ContainsAny(email, " \t\r\n") {
+			return fmt.Errorf("invalid email %q; must be of the form user@domain", email)
+		}
+	}
+	return nil
+}
+
+// nistCurves are the NIST curves by name, "p256", "p384" and "p521" are accepted as aliases.
+var nistCurves = map[string]packet.Curve{
+	"nistp256": packet.CurveNistP256,
+	"nistp384": packet.CurveNistP384,
+	"nistp521": packet.CurveNistP521,
+	"p256":     packet.CurveNistP256,
+	"p384":     packet.CurveNistP384,
+	"p521":     packet.CurveNistP521,
+}
+
+// nistCurveNames lists the names of the NIST curves in the error messages.
+const nistCurveNames = `"nistp256", "nistp384" or "nistp521"`
+
+// keyConfig returns the configuration to generate a key with the given algorithm, curve, size and validity period.
+// The default curve of the algorithm is used if the curve is empty.
+func keyConfig(algorithm string, curve string, keyBits int, keyExpires int) (*packet.Config, error) {
+	config := &packet.Config{
+		DefaultCompressionAlgo: keyCompressionAlgo,
+	}
+	switch algorithm {
+	case "rsa":
+		if curve != "" {
+			return nil, fmt.Errorf("a curve cannot be used with the rsa algorithm")
+		}
+		config.Algorithm = packet.PubKeyAlgoRSA
+		config.RSABits = keyBits
+	case "ecdsa":
+		config.Algorithm = packet.PubKeyAlgoECDSA
+		if curve == "" {
+			curve = "nistp256"
+		}
+		nistCurve, ok := nistCurves[curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %s for the ecdsa algorithm; must be %s", curve, nistCurveNames)
+		}
+		config.Curve = nistCurve
+	case "eddsa":
+		if curve != "" && curve != "curve25519" {
+			return nil, fmt.Errorf("unsupported curve %s for the eddsa algorithm; must be \"curve25519\"", curve)
+		}
+		config.Algorithm = packet.PubKeyAlgoEdDSA
+		config.Curve = packet.Curve25519
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s; must be \"rsa\", \"ecdsa\" or \"eddsa\"", algorithm)
+	}
+	if err := setKeyLifetime(config, keyExpires); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func setKeyLifetime(config *packet.Config, keyExpires int) error {
+	if keyExpires < 0 || keyExpires > math.MaxUint32 {
+		return fmt.Errorf("invalid key_expires %d; must be between 0 and %d seconds", keyExpires, math.MaxUint32)
+	}
+	config.KeyLifetimeSecs = uint32(keyExpires)
+	return nil
+}
+
+// keyCompressionAlgo is advertised in the preferences of the generated keys, in addition to no compression,
+// so messages encrypted with them can be compressed.
+const keyCompressionAlgo = packet.CompressionZLIB
+
+// seededKeyCreationTime is the creation time of the seeded keys so the same seed always gives the same key.
+var seededKeyCreationTime = time.Unix(0, 0)
+
+// seededReader returns a deterministic stream of bytes derived from the seed.
+// It must only be used to generate keys for testing purposes.
+func seededReader(seed []byte) io.Reader {
+	key := sha256.Sum256(seed)
+	block, _ := aes.NewCipher(key[:])
+	return cipher.StreamReader{
+		S: cipher.NewCTR(block, make([]byte, aes.BlockSize)),
+		R: zeroReader{},
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// identity is a user ID of a generated key.
+type identity struct {
+	realName string
+	comment  string
+	email    string
+}
+
+// keyPreferences are the algorithms advertised as preferred by the self-signatures of a generated key.
+// A nil list keeps the default preferences.
+type keyPreferences struct {
+	symmetric   []uint8
+	hash        []uint8
+	compression []uint8
+}
+
+// parseKeyPreferences reads the preferred algorithms given by name.
+func parseKeyPreferences(ciphers, hashes, compression []string) (keyPreferences, error) {
+	var preferences keyPreferences
+	for _, name := range ciphers {
+		var cipher packet.CipherFunction
+		switch name {
+		case "aes128":
+			cipher = packet.CipherAES128
+		case "aes192":
+			cipher = packet.CipherAES192
+		case "aes256":
+			cipher = packet.CipherAES256
+		default:
+			return keyPreferences{}, fmt.Errorf("unsupported preferred cipher %s; must be \"aes128\", \"aes192\" or \"aes256\"", name)
+		}
+		preferences.symmetric = append(preferences.symmetric, uint8(cipher))
+	}
+	for _, name := range hashes {
+		hash, ok := hashAlgorithm(name)
+		if !ok {
+			return keyPreferences{}, fmt.Errorf("unsupported preferred hash %s; must be \"sha2-224\", \"sha2-256\", \"sha2-384\" or \"sha2-512\"", name)
+		}
+		id, _ := openpgp.HashToHashId(hash)
+		preferences.hash = append(preferences.hash, id)
+	}
+	for _, name := range compression {
+		var algo packet.CompressionAlgo
+		switch name {
+		case "none":
+			algo = packet.CompressionNone
+		case "zip":
+			algo = packet.CompressionZIP
+		case "zlib":
+			algo = packet.CompressionZLIB
+		default:
+			return keyPreferences{}, fmt.Errorf("unsupported preferred compression %s; must be \"none\", \"zip\" or \"zlib\"", name)
+		}
+		preferences.compression = append(preferences.compression, uint8(algo))
+	}
+	return preferences, nil
+}
+
+// signaturePreferences returns the preferences declared by the self-signature.
+func signaturePreferences(selfSignature *packet.Signature) keyPreferences {
+	if selfSignature == nil {
+		return keyPreferences{}
+	}
+	return keyPreferences{
+		symmetric:   selfSignature.PreferredSymmetric,
+		hash:        selfSignature.PreferredHash,
+		compression: selfSignature.PreferredCompression,
+	}
+}
+
+// apply sets the preferences on the self-signature, it must be signed again afterwards.
+func (p keyPreferences) apply(selfSignature *packet.Signature) {
+	if p.symmetric != nil {
+		selfSignature.PreferredSymmetric = p.symmetric
+	}
+	if p.hash != nil {
+		selfSignature.PreferredHash = p.hash
+	}
+	if p.compression != nil {
+		selfSignature.PreferredCompression = p.compression
+	}
+}
+
+// parseIdentities reads the identities given as a list of objects with real_name, email and comment fields.
+func parseIdentities(rawIdentities []interface{}) ([]identity, error) {
+	identities := make([]identity, 0, len(rawIdentities))
+	seen := make(map[string]bool, len(rawIdentities))
+	for i, rawIdentity := range rawIdentities {
+		fields, ok := rawIdentity.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid identity %d; must be an object with real_name, email and comment fields", i)
+		}
+		values := make(map[string]string, len(fields))
+		for name, value := range fields {
+			switch name {
+			case "real_name", "email", "comment":
+			default:
+				return nil, fmt.Errorf("invalid identity %d; unknown field %s", i, name)
+			}
+			values[name], ok = value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid identity %d; %s must be a string", i, name)
+			}
+		}
+		id := identity{realName: values["real_name"], comment: values["comment"], email: values["email"]}
+		userID := packet.NewUserId(id.realName, id.comment, id.email)
+		if userID != nil {
+			if seen[userID.Id] {
+				return nil, fmt.Errorf("duplicate identity %s", userID.Id)
+			}
+			seen[userID.Id] = true
+		}
+		identities = append(identities, id)
+	}
+	return identities, nil
+}
+
+// subkeySpec is a subkey to generate with a new key.
+type subkeySpec struct {
+	usage  string
+	config *packet.Config
+}
+
+// parseSubkeySpecs returns the subkeys to generate with a new key, their algorithm defaults to the algorithm of
+// the primary key.
+func parseSubkeySpecs(rawSubkeys []interface{}, defaultAlgorithm string, policy *configEntry, keyExpires int) ([]subkeySpec, error) {
+	subkeys := make([]subkeySpec, 0, len(rawSubkeys))
+	for i, rawSubkey := range rawSubkeys {
+		fields, ok := rawSubkey.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid subkey %d; must be an object with usage, algorithm, curve and key_bits fields", i)
+		}
+		values := map[string]string{"usage": "encrypt", "algorithm": defaultAlgorithm}
+		keyBits := policy.DefaultRSABits
+		for name, value := range fields {
+			switch name {
+			case "usage", "algorithm", "curve":
+				if values[name], ok = value.(string); !ok {
+					return nil, fmt.Errorf("invalid subkey %d; %s must be a string", i, name)
+				}
+			case "key_bits":
+				bits, err := parseutil.ParseInt(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid subkey %d; key_bits must be an integer", i)
+				}
+				keyBits = int(bits)
+			default:
+				return nil, fmt.Errorf("invalid subkey %d; unknown field %s", i, name)
+			}
+		}
+		if values["usage"] != "encrypt" && values["usage"] != "sign" {
+			return nil, fmt.Errorf("invalid subkey %d; unsupported usage %s, must be \"encrypt\" or \"sign\"", i, values["usage"])
+		}
+		config, err := keyConfig(values["algorithm"], values["curve"], keyBits, keyExpires)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subkey %d; %s", i, err)
+		}
+		if err = policy.check(values["algorithm"], keyBits); err != nil {
+			return nil, fmt.Errorf("invalid subkey %d; %s", i, err)
+		}
+		subkeys = append(subkeys, subkeySpec{usage: values["usage"], config: config})
+	}
+	return subkeys, nil
+}
+
+// entityIdentities returns the identities of the entity that are not revoked, the primary one first.
+func entityIdentities(entity *openpgp.Entity) []*openpgp.Identity {
+	now := time.Now()
+	primary := entity.PrimaryIdentity()
+	names := make([]string, 0, len(entity.Identities))
+	for name, id := range entity.Identities {
+		if id != primary && !id.Revoked(now) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	identities := make([]*openpgp.Identity, 0, len(entity.Identities))
+	if primary != nil && !primary.Revoked(now) {
+		identities = append(identities, primary)
+	}
+	for _, name := range names {
+		identities = append(identities, entity.Identities[name])
+	}
+	return identities
+}
+
+// primaryKeyFlags are the capabilities the primary key of a generated key can have.
+var primaryKeyFlags = []string{"certify", "sign", "authenticate"}
+
+// validatePrimaryFlags checks the capabilities can be asserted by the primary key of a generated key.
+func validatePrimaryFlags(flags []string) error {
+	for _, flag := range flags {
+		if !strutil.StrListContains(primaryKeyFlags, flag) {
+			return fmt.Errorf("unsupported primary flag %s; must be \"certify\", \"sign\" or \"authenticate\"", flag)
+		}
+	}
+	if !strutil.StrListContains(flags, "certify") {
+		return fmt.Errorf("the primary key must have the certify flag")
+	}
+	return nil
+}
+
+// subkeyKeyFlags are the capabilities a subkey can be given on import.
+var subkeyKeyFlags = []string{"sign", "encrypt", "authenticate"}
+
+// applySubkeyFlags replaces the capabilities asserted by the binding signatures of the subkeys matching the
+// fingerprints, the new binding signatures are made with the decrypted primary key.
+func applySubkeyFlags(entity *openpgp.Entity, subkeyFlags map[string]string) error {
+	config := &packet.Config{}
+	for fingerprint, value := range subkeyFlags {
+		subkey, ok := findSubkey(entity, fingerprint)
+		if !ok {
+			return fmt.Errorf("subkey %s not found", fingerprint)
+		}
+		var flags []string
+		for _, flag := range strings.Split(value, ",") {
+			flag = strings.TrimSpace(flag)
+			if !strutil.StrListContains(subkeyKeyFlags, flag) {
+				return fmt.Errorf("unsupported flag %q for subkey %s; must be \"sign\", \"encrypt\" or \"authenticate\"", flag, fingerprint)
+			}
+			flags = append(flags, flag)
+		}
+		canSign := subkey.PublicKey.PubKeyAlgo.CanSign()
+		if (strutil.StrListContains(flags, "sign") || strutil.StrListContains(flags, "authenticate")) && !canSign {
+			return fmt.Errorf("subkey %s cannot sign or authenticate with its algorithm", fingerprint)
+		}
+		if strutil.StrListContains(flags, "encrypt") && !subkey.PublicKey.PubKeyAlgo.CanEncrypt() {
+			return fmt.Errorf("subkey %s cannot encrypt with its algorithm", fingerprint)
+		}
+
+		sig := &packet.Signature{
+			Version:                   subkey.Sig.Version,
+			SigType:                   packet.SigTypeSubkeyBinding,
+			PubKeyAlgo:                entity.PrimaryKey.PubKeyAlgo,
+			Hash:                      config.Hash(),
+			CreationTime:              config.Now(),
+			IssuerKeyId:               &entity.PrimaryKey.KeyId,
+			KeyLifetimeSecs:           subkey.Sig.KeyLifetimeSecs,
+			FlagsValid:                true,
+			FlagSign:                  strutil.StrListContains(flags, "sign"),
+			FlagEncryptCommunications: strutil.StrListContains(flags, "encrypt"),
+			FlagEncryptStorage:        strutil.StrListContains(flags, "encrypt"),
+			FlagAuthenticate:          strutil.StrListContains(flags, "authenticate"),
+			EmbeddedSignature:         subkey.Sig.EmbeddedSignature,
+		}
+		// A signing subkey must prove it agrees to be bound to the primary key with a cross-signature.
+		if sig.FlagSign && sig.EmbeddedSignature == nil {
+			if subkey.PrivateKey == nil || subkey.PrivateKey.Dummy() {
+				return fmt.Errorf("subkey %s requires its private key to be given the sign flag", fingerprint)
+			}
+			sig.EmbeddedSignature = &packet.Signature{
+				Version:      subkey.PublicKey.Version,
+				SigType:      packet.SigTypePrimaryKeyBinding,
+				PubKeyAlgo:   subkey.PublicKey.PubKeyAlgo,
+				Hash:         config.Hash(),
+				CreationTime: sig.CreationTime,
+				IssuerKeyId:  &subkey.PublicKey.KeyId,
+			}
+			if err := sig.EmbeddedSignature.CrossSignKey(subkey.PublicKey, entity.PrimaryKey, subkey.PrivateKey, config); err != nil {
+				return err
+			}
+		}
+		if err := sig.SignKey(subkey.PublicKey, entity.PrivateKey, config); err != nil {
+			return err
+		}
+		subkey.Sig = sig
+	}
+	return nil
+}
+
+// generateEntityContext generates the entity like generateEntity but returns as soon as the context is done, for
+// example when the request times out while a large RSA key is generated. The generation itself cannot be
+// interrupted, it completes in the background and its result is discarded.
+func generateEntityContext(ctx context.Context, identities []identity, primaryFlags []string, preferences keyPreferences, config *packet.Config, subkeys []subkeySpec) (*openpgp.Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("the key generation was not started: %s", err)
+	}
+	type result struct {
+		entity *openpgp.Entity
+		err    error
+	}
+	generated := make(chan result, 1)
+	go func() {
+		entity, err := generateEntity(identities, primaryFlags, preferences, config, subkeys)
+		generated <- result{entity: entity, err: err}
+	}()
+	select {
+	case r := <-generated:
+		return r.entity, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("the key generation did not complete before the end of the request: %s", ctx.Err())
+	}
+}
+
+// generateEntity creates a new entity whose primary key has the given capabilities and preferences and
+// whose subkeys share the key lifetime of the primary key. The encryption subkey generated with the primary key is
+// replaced by the given subkeys if there are any.
+func generateEntity(identities []identity, primaryFlags []string, preferences keyPreferences, config *packet.Config, subkeys []subkeySpec) (*openpgp.Entity, error) {
+	primary := identities[0]
+	entity, err := openpgp.NewEntity(primary.realName, primary.comment, primary.email, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(subkeys) > 0 {
+		entity.Subkeys = nil
+		for _, subkey := range subkeys {
+			subkey.config.Rand = config.Rand
+			subkey.config.Time = config.Time
+			if subkey.usage == "sign" {
+				err = entity.AddSigningSubkey(subkey.config)
+			} else {
+				err = entity.AddEncryptionSubkey(subkey.config)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, id := range identities[1:] {
+		if err = entity.AddUserId(id.realName, id.comment, id.email, config); err != nil {
+			return nil, err
+		}
+	}
+	// The flags are asserted by the self-signature of each identity.
+	for _, id := range entity.Identities {
+		id.SelfSignature.FlagsValid = true
+		id.SelfSignature.FlagCertify = strutil.StrListContains(primaryFlags, "certify")
+		id.SelfSignature.FlagSign = strutil.StrListContains(primaryFlags, "sign")
+		id.SelfSignature.FlagAuthenticate = strutil.StrListContains(primaryFlags, "authenticate")
+		id.SelfSignature.FlagEncryptCommunications = false
+		id.SelfSignature.FlagEncryptStorage = false
+		preferences.apply(id.SelfSignature)
+		if err = id.SelfSignature.SignUserId(id.UserId.Id, entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+			return nil, err
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		subkey.Sig.KeyLifetimeSecs = &config.KeyLifetimeSecs
+		err = subkey.Sig.SignKey(subkey.PublicKey, entity.PrivateKey, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entity, nil
+}
+
+func (b *backend) pathKeyDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	resp := &logical.Response{}
+	if entry == nil {
+		resp.AddWarning(fmt.Sprintf("key %s does not exist, nothing has been deleted", name))
+		return resp, nil
+	}
+
+	policy, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if policy.RequireDeleteConfirmation && !data.Get("confirm").(bool) {
+		return logical.ErrorResponse(fmt.Sprintf("deleting key %s requires confirm to be true; the data encrypted for it can no longer be decrypted and its signatures can no longer be verified", name)), logical.ErrInvalidRequest
+	}
+
+	if err = req.Storage.Delete(ctx, "key/"+name); err != nil {
+		return nil, err
+	}
+	if err = req.Storage.Delete(ctx, "usage/"+name); err != nil {
+		return nil, err
+	}
+	b.usageTracker.forget(name)
+	resp.AddWarning(fmt.Sprintf("key %s has been deleted; the data encrypted for it can no longer be decrypted and its signatures can no longer be verified", name))
+	return resp, nil
 }
 
 func (b *backend) pathKeyList(
 	ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	entries, err := req.Storage.List(ctx, "key/")
+	folder := d.Get("folder").(string)
+	entries, err := b.keyNames(ctx, req.Storage, folder)
 	if err != nil {
 		return nil, err
 	}
-	return logical.ListResponse(entries), nil
+	detailed := d.Get("detailed").(bool)
+	tag := d.Get("tag").(string)
+	if !detailed && tag == "" {
+		return logical.ListResponse(entries), nil
+	}
+
+	keys := make([]string, 0, len(entries))
+	keyInfo := make(map[string]interface{}, len(entries))
+	for _, name := range entries {
+		entry, err := b.key(ctx, req.Storage, folder+name)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		if tag != "" && !entry.hasTag(tag) {
+			continue
+		}
+		keys = append(keys, name)
+		if !detailed {
+			continue
+		}
+		entity, err := b.entity(entry)
+		if err != nil {
+			return nil, err
+		}
+		keyInfo[name] = map[string]interface{}{
+			"fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
+			"algorithm":   entry.Algorithm,
+			"exportable":  entry.Exportable,
+			"enabled":     entry.Enabled,
+			"tags":        entry.tags(),
+		}
+	}
+	if !detailed {
+		return logical.ListResponse(keys), nil
+	}
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
 }
 
 type keyEntry struct {
 	SerializedKey []byte
 	Exportable    bool
+	PreviousKeys  [][]byte
+	CreationTime  time.Time
+	Algorithm     string
+	KeyBits       int
+	// UsableUntil is the end of the usage TTL of the key, the zero value means the key is usable forever
+	UsableUntil time.Time
+	Tags        map[string]string
+	// Enabled is false when the key is disabled, a disabled key cannot be used to sign, encrypt or decrypt
+	Enabled bool
+	// MaxOperationsPerSecond limits the sign and decrypt operations made with the key, zero means no limit
+	MaxOperationsPerSecond int
+	// KeySource is "pkcs11" when the private key is stored in a PKCS#11 token, SerializedKey then only holds the
+	// public key and the private key is referenced by the token and key labels
+	KeySource        string
+	PKCS11TokenLabel string
+	PKCS11KeyLabel   string
+}
+
+// sameImport reports whether an imported key entry would store the same key material, with or without its private
+// key, and the same attributes as the entry.
+func (entry *keyEntry) sameImport(imported *keyEntry) bool {
+	return bytes.Equal(entry.SerializedKey, imported.SerializedKey) &&
+		entry.Exportable == imported.Exportable &&
+		reflect.DeepEqual(entry.tags(), imported.tags()) &&
+		entry.MaxOperationsPerSecond == imported.MaxOperationsPerSecond &&
+		entry.UsableUntil.IsZero() && imported.UsableUntil.IsZero() &&
+		entry.KeySource == imported.KeySource &&
+		entry.PKCS11TokenLabel == imported.PKCS11TokenLabel &&
+		entry.PKCS11KeyLabel == imported.PKCS11KeyLabel
+}
+
+// keySource returns where the private key is stored, "vault" or "pkcs11".
+func (entry *keyEntry) keySource() string {
+	if entry.KeySource == "" {
+		return "vault"
+	}
+	return entry.KeySource
+}
+
+// checkKeyMaterialUpdatable returns an error if the key material cannot be modified by Vault because the private key
+// is stored in a PKCS#11 token.
+func (entry *keyEntry) checkKeyMaterialUpdatable() error {
+	if entry.KeySource == keySourcePKCS11 {
+		return fmt.Errorf("the key material of a key stored in a PKCS#11 token cannot be modified")
+	}
+	return nil
+}
+
+// tags returns the tags of the key, never nil.
+func (entry *keyEntry) tags() map[string]string {
+	if entry.Tags == nil {
+		return map[string]string{}
+	}
+	return entry.Tags
+}
+
+// hasTag reports whether the key has the tag given as "key:value", or as "key" to match any value.
+func (entry *keyEntry) hasTag(tag string) bool {
+	parts := strings.SplitN(tag, ":", 2)
+	value, ok := entry.Tags[parts[0]]
+	if !ok {
+		return false
+	}
+	return len(parts) == 1 || value == parts[1]
+}
+
+// validateTags returns an error if a tag key cannot be used to filter the keys.
+func validateTags(tags map[string]string) error {
+	for key := range tags {
+		if key == "" || strings.Contains(key, ":") {
+			return fmt.Errorf("invalid tag key %q; must not be empty or contain \":\"", key)
+		}
+	}
+	return nil
+}
+
+// checkUsable returns an error if the key is disabled or if its usage TTL has elapsed.
+func (entry *keyEntry) checkUsable() error {
+	if err := entry.checkEnabled(); err != nil {
+		return err
+	}
+	return entry.checkUsageTTL()
+}
+
+// checkUsageTTL returns an error if the usage TTL of the key has elapsed.
+func (entry *keyEntry) checkUsageTTL() error {
+	if !entry.UsableUntil.IsZero() && time.Now().After(entry.UsableUntil) {
+		return fmt.Errorf("the usage TTL of the key expired at %s", entry.UsableUntil.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkEnabled returns an error if the key is disabled.
+func (entry *keyEntry) checkEnabled() error {
+	if !entry.Enabled {
+		return fmt.Errorf("the key is disabled")
+	}
+	return nil
+}
+
+// setMetadata fills the metadata of the key entry from the primary key of the entity.
+func (entry *keyEntry) setMetadata(entity *openpgp.Entity) error {
+	entry.CreationTime = entity.PrimaryKey.CreationTime
+	entry.Algorithm = publicKeyAlgorithmName(entity.PrimaryKey.PubKeyAlgo)
+	bitLength, err := entity.PrimaryKey.BitLength()
+	if err != nil {
+		return err
+	}
+	entry.KeyBits = int(bitLength)
+	return nil
+}
+
+// minRecommendedRSABits is the size below which RSA keys are deprecated.
+const minRecommendedRSABits = 2048
+
+// importWarnings returns the weaknesses of the primary key and the subkeys of an imported entity: deprecated
+// algorithms, small RSA keys and the algorithms not allowed by the configuration.
+func (config *configEntry) importWarnings(entity *openpgp.Entity) []string {
+	var warnings []string
+	check := func(description string, pk *packet.PublicKey) {
+		algorithm := publicKeyAlgorithmName(pk.PubKeyAlgo)
+		switch pk.PubKeyAlgo {
+		case packet.PubKeyAlgoDSA, packet.PubKeyAlgoElGamal:
+			warnings = append(warnings, fmt.Sprintf("the %s uses the deprecated %s algorithm", description, algorithm))
+		case packet.PubKeyAlgoECDH:
+			// ECDH subkeys come with the ecdsa and eddsa keys
+		default:
+			if !strutil.StrListContains(config.AllowedAlgorithms, algorithm) {
+				warnings = append(warnings, fmt.Sprintf("the %s uses the %s algorithm, not allowed by the allowed_algorithms configuration", description, algorithm))
+			}
+		}
+		if algorithm != "rsa" {
+			return
+		}
+		bitLength, err := pk.BitLength()
+		if err != nil {
+			return
+		}
+		if int(bitLength) < config.MinRSABits {
+			warnings = append(warnings, fmt.Sprintf("the %s is a %d-bit RSA key, smaller than the min_rsa_bits configuration of %d bits", description, bitLength, config.MinRSABits))
+		} else if bitLength < minRecommendedRSABits {
+			warnings = append(warnings, fmt.Sprintf("the %s is a %d-bit RSA key, RSA keys smaller than %d bits are deprecated", description, bitLength, minRecommendedRSABits))
+		}
+	}
+	check("primary key", entity.PrimaryKey)
+	for _, subkey := range entity.Subkeys {
+		check("subkey "+hex.EncodeToString(subkey.PublicKey.Fingerprint), subkey.PublicKey)
+	}
+	return warnings
+}
+
+func publicKeyAlgorithmName(algorithm packet.PublicKeyAlgorithm) string {
+	switch algorithm {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+		return "rsa"
+	case packet.PubKeyAlgoDSA:
+		return "dsa"
+	case packet.PubKeyAlgoElGamal:
+		return "elgamal"
+	case packet.PubKeyAlgoECDSA:
+		return "ecdsa"
+	case packet.PubKeyAlgoECDH:
+		return "ecdh"
+	case packet.PubKeyAlgoEdDSA:
+		return "eddsa"
+	case packet.PubKeyAlgoEd25519:
+		return "ed25519"
+	case packet.PubKeyAlgoEd448:
+		return "ed448"
+	case packet.PubKeyAlgoX25519:
+		return "x25519"
+	case packet.PubKeyAlgoX448:
+		return "x448"
+	default:
+		return fmt.Sprintf("unknown (%d)", algorithm)
+	}
+}
+
+// keyStrength returns a summary of the algorithm and size of a public key, such as RSA-4096,
+// Ed25519 or ECDSA-P384.
+func keyStrength(pk *packet.PublicKey) string {
+	switch pk.PubKeyAlgo {
+	case packet.PubKeyAlgoEd25519:
+		return "Ed25519"
+	case packet.PubKeyAlgoEd448:
+		return "Ed448"
+	case packet.PubKeyAlgoX25519:
+		return "X25519"
+	case packet.PubKeyAlgoX448:
+		return "X448"
+	case packet.PubKeyAlgoECDSA, packet.PubKeyAlgoECDH, packet.PubKeyAlgoEdDSA:
+		curve, err := pk.Curve()
+		if err != nil {
+			return strings.ToUpper(publicKeyAlgorithmName(pk.PubKeyAlgo))
+		}
+		if pk.PubKeyAlgo == packet.PubKeyAlgoEdDSA {
+			switch curve {
+			case packet.Curve25519:
+				return "Ed25519"
+			case packet.Curve448:
+				return "Ed448"
+			}
+		}
+		return strings.ToUpper(publicKeyAlgorithmName(pk.PubKeyAlgo)) + "-" + string(curve)
+	}
+
+	name := strings.ToUpper(publicKeyAlgorithmName(pk.PubKeyAlgo))
+	if pk.PubKeyAlgo == packet.PubKeyAlgoElGamal {
+		name = "ElGamal"
+	}
+	bitLength, err := pk.BitLength()
+	if err != nil {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, bitLength)
 }
 
 const pathPolicyHelpSyn = "Managed named GPG keys"
@@ -262,3 +2101,10 @@ This path is used to manage the named GPG keys that are available.
 Doing a write with no value against a new named key will create
 it using a randomly generated key.
 `
+
+const pathKeyByFingerprintHelpSyn = "Read a GPG key by fingerprint"
+const pathKeyByFingerprintHelpDesc = `
+This path returns the named GPG key, including its name, whose primary key or
+one of its subkeys matches the fingerprint. Previous versions of the keys are
+also considered.
+`
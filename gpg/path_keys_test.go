@@ -1,9 +1,21 @@
 package gpg
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGPG_CreateNotGeneratedKeyWithoutKeyError(t *testing.T) {
@@ -51,6 +63,15 @@ func TestGPG_CreateErrorGeneratedKeyWithInvalidKey(t *testing.T) {
 	if !response.IsError() {
 		t.Fatal("Key was not a ASCII-armored key but has been created")
 	}
+
+	req.Data["key"] = gpgKey[:len(gpgKey)/2]
+	response, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.IsError() || !strings.HasPrefix(response.Error().Error(), "failed to parse armored key: ") {
+		t.Fatalf("expected to fail with a hint about the truncated armored key, got %#v", response)
+	}
 }
 
 func TestGPG_CreateErrorGeneratedKeyWithOnlyPublicKey(t *testing.T) {
@@ -100,6 +121,397 @@ func TestGPG_CreateErrorGeneratedKeyTooSmallKeyBits(t *testing.T) {
 	}
 }
 
+func TestGPG_CreateKeyAlgorithms(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	algorithms := map[string]packet.PublicKeyAlgorithm{
+		"rsa":   packet.PubKeyAlgoRSA,
+		"ecdsa": packet.PubKeyAlgoECDSA,
+		"eddsa": packet.PubKeyAlgoEdDSA,
+	}
+	strengths := map[string]string{
+		"rsa":   "RSA-2048",
+		"ecdsa": "ECDSA-P256",
+		"eddsa": "Ed25519",
+	}
+	for algorithm, pubKeyAlgo := range algorithms {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + algorithm,
+			Data: map[string]interface{}{
+				"real_name": "Vault GPG test",
+				"algorithm": algorithm,
+				"key_bits":  1024,
+			},
+		}
+		if algorithm == "rsa" {
+			req.Data["key_bits"] = 2048
+		}
+		response, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.IsError() {
+			t.Fatalf("not expected error response for algorithm %s: %#v", algorithm, *response)
+		}
+
+		reqRead := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/" + algorithm,
+		}
+		response, err = b.HandleRequest(context.Background(), reqRead)
+		if err != nil {
+			t.Fatal(err)
+		}
+		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(response.Data["public_key"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if el[0].PrimaryKey.PubKeyAlgo != pubKeyAlgo {
+			t.Fatalf("expected public key algorithm %d for %s, got %d", pubKeyAlgo, algorithm, el[0].PrimaryKey.PubKeyAlgo)
+		}
+		if response.Data["algorithm"] != algorithm {
+			t.Fatalf("expected algorithm %s, got %s", algorithm, response.Data["algorithm"])
+		}
+		if response.Data["creation_time"] != el[0].PrimaryKey.CreationTime.UTC().Format(time.RFC3339) {
+			t.Fatalf("unexpected creation time %s", response.Data["creation_time"])
+		}
+		if response.Data["strength"] != strengths[algorithm] {
+			t.Fatalf("expected strength %s, got %s", strengths[algorithm], response.Data["strength"])
+		}
+		if !reflect.DeepEqual(response.Data["capabilities"], []string{"certify", "sign"}) {
+			t.Fatalf("unexpected capabilities for %s: %#v", algorithm, response.Data["capabilities"])
+		}
+	}
+}
+
+func TestGPG_CreateErrorGeneratedKeyUnsupportedAlgorithm(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"algorithm": "dsa",
+		},
+	}
+	response, err := b.HandleRequest(context.Background(), req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.IsError() {
+		t.Fatal("Key creation has been accepted but should have denied due to unsupported algorithm")
+	}
+}
+
+func TestGPG_CreateKeyWithExpiration(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	readKey := func(name string) map[string]interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/" + name,
+		}
+		response, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.IsError() {
+			t.Fatalf("not expected error response: %#v", *response)
+		}
+		return response.Data
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name":   "Vault GPG test",
+			"key_expires": "24h",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := readKey("test")
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := el[0].PrimaryKey.CreationTime.Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	if data["expires_at"] != expected {
+		t.Fatalf("expected expiration %s, got %v", expected, data["expires_at"])
+	}
+	if data["created_unix"] != el[0].PrimaryKey.CreationTime.Unix() || data["expires_unix"] != el[0].PrimaryKey.CreationTime.Add(24*time.Hour).Unix() {
+		t.Fatalf("expected creation and expiration as epoch seconds, got %v and %v", data["created_unix"], data["expires_unix"])
+	}
+	for _, subkey := range el[0].Subkeys {
+		if subkey.Sig.KeyLifetimeSecs == nil || *subkey.Sig.KeyLifetimeSecs != 24*3600 {
+			t.Fatalf("subkey is expected to expire after 24h")
+		}
+	}
+
+	req.Path = "keys/test2"
+	req.Data["key_expires"] = 0
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expiresAt := readKey("test2")["expires_at"]; expiresAt != nil {
+		t.Fatalf("key is not expected to expire, got %v", expiresAt)
+	}
+
+	req.Path = "keys/test3"
+	req.Data = map[string]interface{}{
+		"generate": false,
+		"key":      gpgKey,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expiresAt := readKey("test3")["expires_at"]; expiresAt != nil {
+		t.Fatalf("imported key is not expected to expire, got %v", expiresAt)
+	}
+	if data := readKey("test3"); data["expires_unix"] != nil || data["created_unix"] != int64(1503231122) {
+		t.Fatalf("expected the imported key to be created at 1503231122 and to not expire, got %v and %v", data["created_unix"], data["expires_unix"])
+	}
+}
+
+func TestGPG_PassphraseProtectedKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	keys := map[string]map[string]interface{}{
+		"generated": {
+			"real_name":  "Vault GPG test",
+			"passphrase": "passphrase",
+		},
+		"imported": {
+			"generate":   false,
+			"key":        gpgKey,
+			"passphrase": "passphrase",
+		},
+	}
+	for name, keyData := range keys {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data:      keyData,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sign := func(passphrase string) *logical.Response {
+			req := &logical.Request{
+				Storage:   storage,
+				Operation: logical.UpdateOperation,
+				Path:      "sign/" + name,
+				Data: map[string]interface{}{
+					"input":      "dGhlIHF1aWNrIGJyb3duIGZveA==",
+					"passphrase": passphrase,
+				},
+			}
+			response, _ := b.HandleRequest(context.Background(), req)
+			return response
+		}
+
+		if !sign("").IsError() {
+			t.Fatalf("key %s is protected by a passphrase but has been used without it", name)
+		}
+		if !sign("wrong").IsError() {
+			t.Fatalf("key %s is protected by a passphrase but has been used with a wrong one", name)
+		}
+		response := sign("passphrase")
+		if response.IsError() {
+			t.Fatalf("not expected error response: %#v", *response)
+		}
+
+		reqVerify := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "verify/" + name,
+			Data: map[string]interface{}{
+				"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+				"signature": response.Data["signature"],
+			},
+		}
+		response, err = b.HandleRequest(context.Background(), reqVerify)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !response.Data["valid"].(bool) {
+			t.Fatalf("signature made with key %s is not valid", name)
+		}
+
+		reqEncrypt := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/" + name,
+			Data: map[string]interface{}{
+				"plaintext": "QWxwYWNhcwo=",
+			},
+		}
+		response, err = b.HandleRequest(context.Background(), reqEncrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqDecrypt := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "decrypt/" + name,
+			Data: map[string]interface{}{
+				"ciphertext": response.Data["ciphertext"],
+				"format":     "ascii-armor",
+			},
+		}
+		response, _ = b.HandleRequest(context.Background(), reqDecrypt)
+		if !response.IsError() {
+			t.Fatalf("key %s is protected by a passphrase but has been used without it", name)
+		}
+		reqDecrypt.Data["passphrase"] = "passphrase"
+		response, err = b.HandleRequest(context.Background(), reqDecrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Data["plaintext"] != "QWxwYWNhcwo=" {
+			t.Fatalf("expected plaintext QWxwYWNhcwo=, got: %s", response.Data["plaintext"])
+		}
+	}
+}
+
+func TestGPG_ImportedKeyMetadata(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      gpgKey,
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	response, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Data["algorithm"] != "rsa" {
+		t.Fatalf("expected algorithm rsa, got %s", response.Data["algorithm"])
+	}
+	if response.Data["key_bits"] != 2048 {
+		t.Fatalf("expected 2048 bits, got %d", response.Data["key_bits"])
+	}
+	if response.Data["strength"] != "RSA-2048" {
+		t.Fatalf("expected strength RSA-2048, got %s", response.Data["strength"])
+	}
+	if response.Data["version"] != 4 {
+		t.Fatalf("expected a V4 key, got version %v", response.Data["version"])
+	}
+	if response.Data["creation_time"] != "2017-08-20T12:12:02Z" {
+		t.Fatalf("unexpected creation time %s", response.Data["creation_time"])
+	}
+	fingerprints := map[string]string{
+		"fingerprint":           "fbbc9a77bb696e6787ef0b5b2f7b5633b6f42527",
+		"formatted_fingerprint": "FBBC 9A77 BB69 6E67 87EF  0B5B 2F7B 5633 B6F4 2527",
+		"key_id":                "2f7b5633b6f42527",
+		"short_key_id":          "b6f42527",
+	}
+	for field, expected := range fingerprints {
+		if response.Data[field] != expected {
+			t.Fatalf("expected %s %s, got %s", field, expected, response.Data[field])
+		}
+	}
+	if !reflect.DeepEqual(response.Data["capabilities"], []string{"certify", "sign"}) {
+		t.Fatalf("unexpected capabilities %#v", response.Data["capabilities"])
+	}
+	subkeys := response.Data["subkeys"].([]map[string]interface{})
+	if len(subkeys) != 1 {
+		t.Fatalf("expected 1 subkey, got %d", len(subkeys))
+	}
+	expected := map[string]interface{}{
+		"fingerprint":   "31c2f5860bbc0dade0e9ebf64fcca897d922fd7d",
+		"creation_time": "2017-08-20T12:12:02Z",
+		"expires_at":    nil,
+		"capabilities":  []string{"encrypt"},
+		"algorithm":     "rsa",
+		"strength":      "RSA-2048",
+	}
+	if !reflect.DeepEqual(subkeys[0], expected) {
+		t.Fatalf("expected subkey %#v, got %#v", expected, subkeys[0])
+	}
+
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "", &packet.Config{Algorithm: packet.PubKeyAlgoRSA, RSABits: 2048, V6Keys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/v6",
+		Data: map[string]interface{}{
+			"generate": false,
+			"key":      buf.String(),
+		},
+	}
+	if response, err = b.HandleRequest(context.Background(), req); err != nil || response.IsError() {
+		t.Fatalf("not expected error %v %#v", err, response)
+	}
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/v6",
+	}
+	response, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Data["version"] != 6 {
+		t.Fatalf("expected a V6 key, got version %v", response.Data["version"])
+	}
+}
+
 const gpgPublicKey = `-----BEGIN PGP PUBLIC KEY BLOCK-----
 
 mQENBFmZfJIBCACx2NgAf4rLLx2QKo444ATs3ewJICdy/cYhETxcn5wewdrxQayJ
@@ -130,3 +542,1761 @@ qKqjtePx5gnMoyVXnDqgwsbxPh6GdKGx+Rgt47o1bXm/o8PSpA5Qbb3xVKmAi34b
 ZfOYAeX554UB1xwK6a/T3rHf3eZM4Oc64dsmbhRftQ==
 =G71q
 -----END PGP PUBLIC KEY BLOCK-----`
+
+func TestGPG_CreateSeededKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	createKey := func(name string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	readFingerprints := func(name string) []interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/" + name,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []interface{}{resp.Data["fingerprint"], resp.Data["subkeys"].([]map[string]interface{})[0]["fingerprint"]}
+	}
+
+	seededKey := map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+		"seed":      "000102030405060708090a0b0c0d0e0f",
+	}
+	if resp := createKey("test1", seededKey); !resp.IsError() {
+		t.Fatal("expected to fail, seeded keys are not allowed by default")
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data: map[string]interface{}{
+			"allow_seeded_keys": true,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := createKey("test1", seededKey); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp := createKey("test2", seededKey); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if !reflect.DeepEqual(readFingerprints("test1"), readFingerprints("test2")) {
+		t.Fatal("keys generated from the same seed must be identical")
+	}
+
+	seededKey["seed"] = "0f0e0d0c0b0a09080706050403020100"
+	if resp := createKey("test3", seededKey); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if reflect.DeepEqual(readFingerprints("test1"), readFingerprints("test3")) {
+		t.Fatal("keys generated from different seeds must be different")
+	}
+
+	seededKey["seed"] = "Not hex"
+	if resp := createKey("test4", seededKey); !resp.IsError() {
+		t.Fatal("expected to fail, seed is not hex encoded")
+	}
+	seededKey["seed"] = "000102030405060708090a0b0c0d0e0f"
+	seededKey["algorithm"] = "rsa"
+	if resp := createKey("test4", seededKey); !resp.IsError() {
+		t.Fatal("expected to fail, seeded RSA keys are not supported")
+	}
+}
+
+func TestGPG_CreateKeyCreationTime(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	for _, creationTime := range []string{"yesterday", "1969-12-31T23:59:59Z", time.Now().Add(time.Hour).Format(time.RFC3339)} {
+		if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+			"real_name":     "Vault GPG test",
+			"algorithm":     "eddsa",
+			"creation_time": creationTime,
+		}); !resp.IsError() {
+			t.Fatalf("expected to fail, %s is not a valid creation time", creationTime)
+		}
+	}
+
+	handle(logical.UpdateOperation, "config", map[string]interface{}{"allow_seeded_keys": true})
+	key := map[string]interface{}{
+		"real_name":     "Vault GPG test",
+		"algorithm":     "eddsa",
+		"seed":          "000102030405060708090a0b0c0d0e0f",
+		"creation_time": "2020-06-01T12:00:00+02:00",
+	}
+	handle(logical.UpdateOperation, "keys/test1", key)
+	handle(logical.UpdateOperation, "keys/test2", key)
+	first := handle(logical.ReadOperation, "keys/test1", nil).Data
+	if first["creation_time"] != "2020-06-01T10:00:00Z" {
+		t.Fatalf("expected the key to be created at 2020-06-01T10:00:00Z, got %v", first["creation_time"])
+	}
+	if subkey := first["subkeys"].([]map[string]interface{})[0]; subkey["creation_time"] != "2020-06-01T10:00:00Z" {
+		t.Fatalf("expected the subkey to be created at 2020-06-01T10:00:00Z, got %v", subkey["creation_time"])
+	}
+	if second := handle(logical.ReadOperation, "keys/test2", nil).Data; first["fingerprint"] != second["fingerprint"] || !reflect.DeepEqual(first["subkeys"], second["subkeys"]) {
+		t.Fatal("keys generated from the same seed and creation time must be identical")
+	}
+}
+
+func TestGPG_ReadLegacyKeyEntry(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"generate": false,
+		"key":      gpgKey,
+	})
+	// Entries stored by older versions only have the key material
+	stored, err := b.key(context.Background(), storage, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := logical.StorageEntryJSON("key/test", map[string]interface{}{
+		"SerializedKey": stored.SerializedKey,
+		"Exportable":    false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.Put(context.Background(), legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	read := handle(logical.ReadOperation, "keys/test", nil)
+	if read.IsError() {
+		t.Fatalf("not expected error response: %#v", *read)
+	}
+	if read.Data["algorithm"] != "rsa" || read.Data["key_bits"] != 2048 || read.Data["creation_time"] != "2017-08-20T12:12:02Z" {
+		t.Fatalf("expected the metadata to be derived from the key material, got %#v", read.Data)
+	}
+
+	storedEntry := func() keyEntry {
+		entry, err := storage.Get(context.Background(), "key/test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded keyEntry
+		if err = entry.DecodeJSON(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		return decoded
+	}
+	if unchanged := storedEntry(); unchanged.Algorithm != "" {
+		t.Fatalf("expected a read to not rewrite the entry, got %#v", unchanged)
+	}
+
+	// The metadata are persisted by the next write of the key
+	if resp := handle(logical.UpdateOperation, "keys/test/rate-limit", map[string]interface{}{"max_operations_per_second": 10}); resp != nil && resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if migrated := storedEntry(); migrated.Algorithm != "rsa" || migrated.KeyBits != 2048 || !migrated.Enabled {
+		t.Fatalf("expected the entry to be written with its metadata, got %#v", migrated)
+	}
+}
+
+func TestGPG_CreateExistingKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	createKey := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/test",
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	readKey := func() map[string]interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/test",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+	readFingerprint := func() interface{} {
+		return readKey()["fingerprint"]
+	}
+
+	keyData := map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	}
+	if resp := createKey(keyData); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	fingerprint := readFingerprint()
+
+	if resp := createKey(keyData); !resp.IsError() {
+		t.Fatal("expected to fail, the key already exists")
+	}
+	if readFingerprint() != fingerprint {
+		t.Fatal("the existing key must not be overwritten")
+	}
+
+	keyData["force"] = true
+	if resp := createKey(keyData); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if readFingerprint() == fingerprint {
+		t.Fatal("the existing key must be overwritten when forced")
+	}
+	fingerprint = readFingerprint()
+
+	keyData["exportable"] = true
+	if resp := createKey(keyData); !resp.IsError() {
+		t.Fatal("expected to fail, a non-exportable key cannot be made exportable")
+	}
+	if readFingerprint() != fingerprint {
+		t.Fatal("the existing key must not be overwritten")
+	}
+
+	importData := map[string]interface{}{"generate": false, "key": gpgKey, "force": true}
+	if resp := createKey(importData); resp.IsError() || resp.Data["no_change"] != false {
+		t.Fatalf("expected the imported key to be stored, got %#v", resp)
+	}
+	stored, err := storage.Get(context.Background(), "key/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(importData, "force")
+	resp := createKey(importData)
+	if resp.IsError() || resp.Data["no_change"] != true || resp.Data["fingerprint"] != readFingerprint() {
+		t.Fatalf("expected importing the same key again to be a no-op, got %#v", resp)
+	}
+	unchanged, err := storage.Get(context.Background(), "key/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored.Value, unchanged.Value) {
+		t.Fatal("the stored key must not be rewritten when the same key is imported")
+	}
+
+	importData["key"] = privateDecryptKey
+	if resp := createKey(importData); !resp.IsError() {
+		t.Fatal("expected to fail, another key already exists")
+	}
+	importData["force"] = true
+	if resp := createKey(importData); resp.IsError() || resp.Data["no_change"] != false {
+		t.Fatalf("expected the other key to overwrite the existing one when forced, got %#v", resp)
+	}
+
+	// The private key of a stored public key is a change even if the fingerprint is the same.
+	publicKey := readKey()["public_key"]
+	publicData := map[string]interface{}{"generate": false, "key": publicKey, "allow_public_only": true, "force": true}
+	if resp := createKey(publicData); resp.IsError() || readKey()["has_private_key"] != false {
+		t.Fatalf("expected the public key to be stored, got %#v", resp)
+	}
+	privateData := map[string]interface{}{"generate": false, "key": privateDecryptKey}
+	if resp := createKey(privateData); !resp.IsError() {
+		t.Fatal("expected to fail, the private key is not stored yet")
+	}
+	privateData["force"] = true
+	if resp := createKey(privateData); resp.IsError() || resp.Data["no_change"] != false {
+		t.Fatalf("expected the private key to be stored, got %#v", resp)
+	}
+	if readKey()["has_private_key"] != true {
+		t.Fatal("expected the stored key to have its private key")
+	}
+	privateData["tags"] = map[string]interface{}{"team": "payments"}
+	if resp := createKey(privateData); resp.IsError() || resp.Data["no_change"] != false {
+		t.Fatalf("expected the tags to be stored, got %#v", resp)
+	}
+	if tags := readKey()["tags"]; !reflect.DeepEqual(tags, map[string]string{"team": "payments"}) {
+		t.Fatalf("expected the new tags to be stored, got %#v", tags)
+	}
+}
+
+func TestGPG_KeyUsageTTL(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+			"usage_ttl": "1h",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usableUntil, err := time.Parse(time.RFC3339, resp.Data["usable_until"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(usableUntil); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("expected the key to be usable for 1 hour, got %s", d)
+	}
+
+	sign := func() *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/test",
+			Data: map[string]interface{}{
+				"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	if resp = sign(); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	entry, err := b.key(context.Background(), storage, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.UsableUntil = time.Now().Add(-time.Minute)
+	storageEntry, err := logical.StorageEntryJSON("key/test", entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.Put(context.Background(), storageEntry); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp = sign(); !resp.IsError() {
+		t.Fatal("expected to fail, the usage TTL of the key has elapsed")
+	}
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "decrypt/test",
+		Data: map[string]interface{}{
+			"ciphertext": "Not a message",
+		},
+	}
+	resp, _ = b.HandleRequest(context.Background(), req)
+	if !resp.IsError() || !strings.Contains(resp.Data["error"].(string), "usage TTL") {
+		t.Fatalf("expected to fail because the usage TTL of the key has elapsed, got %#v", resp)
+	}
+
+	// Signing through encrypt and certify is also prevented.
+	other, err := openpgp.NewEntity("Other", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var publicKey bytes.Buffer
+	w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = other.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	signatures := map[string]map[string]interface{}{
+		"encrypt/test": {"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "signer": true},
+		"certify/test": {"public_key": publicKey.String()},
+	}
+	for path, data := range signatures {
+		resp, _ = b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if !resp.IsError() || !strings.Contains(resp.Data["error"].(string), "usage TTL") {
+			t.Fatalf("expected %s to fail because the usage TTL of the key has elapsed, got %#v", path, resp)
+		}
+	}
+	resp, _ = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt/test",
+		Data:      map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA=="},
+	})
+	if resp.IsError() {
+		t.Fatalf("expected to encrypt without signing, got %#v", *resp)
+	}
+}
+
+func TestGPG_ReadKeyByFingerprint(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	createKey := func(name string, data map[string]interface{}) {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data:      data,
+		}
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createKey("generated", map[string]interface{}{"real_name": "Vault GPG test", "algorithm": "eddsa"})
+	createKey("imported", map[string]interface{}{"generate": false, "key": gpgKey})
+
+	readByFingerprint := func(fingerprint string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/by-fingerprint/" + fingerprint,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	fingerprint := testAccReadFingerprint(t, b, storage, "generated").(string)
+	resp := readByFingerprint(strings.ToUpper(fingerprint))
+	if resp == nil || resp.Data["name"] != "generated" || resp.Data["fingerprint"] != fingerprint {
+		t.Fatalf("expected the generated key to be found, got %#v", resp)
+	}
+	if _, ok := resp.Data["public_key"]; !ok {
+		t.Fatal("expected the public key to be returned")
+	}
+	if resp = readByFingerprint("31c2f5860bbc0dade0e9ebf64fcca897d922fd7d"); resp == nil || resp.Data["name"] != "imported" {
+		t.Fatalf("expected the imported key to be found by its subkey fingerprint, got %#v", resp)
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "rotate/generated",
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if resp = readByFingerprint(fingerprint); resp == nil || resp.Data["name"] != "generated" {
+		t.Fatalf("expected the generated key to be found by its previous fingerprint, got %#v", resp)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/by-fingerprint/0000000000000000000000000000000000000000",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err == nil || !resp.IsError() || !strings.Contains(resp.Data["error"].(string), "0000000000000000000000000000000000000000") {
+		t.Fatalf("expected no key to be found, got %#v", resp)
+	}
+	if codedErr, ok := err.(logical.HTTPCodedError); !ok || codedErr.Code() != http.StatusNotFound {
+		t.Fatalf("expected a not found error, got %#v", err)
+	}
+
+	for _, test := range []struct {
+		fingerprint string
+		data        map[string]interface{}
+	}{
+		{"not-a-fingerprint", nil},
+		{"0123", nil},
+		{fingerprint, map[string]interface{}{"export_format": "unknown"}},
+		{fingerprint, map[string]interface{}{"line_ending": "cr"}},
+	} {
+		req = &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/by-fingerprint/" + test.fingerprint,
+			Data:      test.data,
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest || !resp.IsError() {
+			t.Fatalf("expected an invalid request for %s %#v, got %#v, %v", test.fingerprint, test.data, resp, err)
+		}
+	}
+}
+
+func TestGPG_CreateKeyWithIdentities(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	createKey := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/test",
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	readIdentities := func() interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/test",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data["identities"]
+	}
+
+	work := map[string]interface{}{"real_name": "John Doe", "email": "john.doe@work.example.com"}
+	personal := map[string]interface{}{"real_name": "John Doe", "email": "john@example.com", "comment": "personal"}
+	invalidIdentities := [][]interface{}{
+		{work, work},
+		{work, map[string]interface{}{"email": "not an email"}},
+		{work, map[string]interface{}{"name": "John Doe"}},
+		{"John Doe"},
+	}
+	for _, identities := range invalidIdentities {
+		if resp := createKey(map[string]interface{}{"algorithm": "eddsa", "identities": identities}); !resp.IsError() {
+			t.Fatalf("expected to fail with identities %#v", identities)
+		}
+	}
+	if resp := createKey(map[string]interface{}{"algorithm": "eddsa", "real_name": "John Doe", "identities": []interface{}{work}}); !resp.IsError() {
+		t.Fatal("expected to fail, identities cannot be used with real_name")
+	}
+
+	if resp := createKey(map[string]interface{}{"algorithm": "eddsa", "identities": []interface{}{personal, work}}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	expected := []map[string]interface{}{
+		{"real_name": "John Doe", "email": "john@example.com", "comment": "personal"},
+		{"real_name": "John Doe", "email": "john.doe@work.example.com", "comment": ""},
+	}
+	if identities := readIdentities(); !reflect.DeepEqual(identities, expected) {
+		t.Fatalf("expected identities %#v, got %#v", expected, identities)
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "rotate/test",
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if identities := readIdentities(); !reflect.DeepEqual(identities, expected) {
+		t.Fatalf("expected the rotated key to keep the identities %#v, got %#v", expected, identities)
+	}
+}
+
+func TestGPG_ReadKeyExportFormat(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	readKey := func(exportFormat string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/test",
+			Data: map[string]interface{}{
+				"export_format": exportFormat,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := readKey("binary"); !resp.IsError() {
+		t.Fatal("expected to fail, binary is not a supported export format")
+	}
+
+	resp := readKey("base64")
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(resp.Data["public_key"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, err := openpgp.ReadKeyRing(bytes.NewReader(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint := hex.EncodeToString(el[0].PrimaryKey.Fingerprint[:]); fingerprint != resp.Data["fingerprint"] {
+		t.Fatalf("expected the binary public key to have the fingerprint %s, got %s", resp.Data["fingerprint"], fingerprint)
+	}
+	if el[0].PrivateKey != nil {
+		t.Fatal("the private key must not be returned")
+	}
+
+	resp = readKey("armored")
+	if !strings.HasPrefix(resp.Data["public_key"].(string), "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+		t.Fatalf("expected an armored public key, got %s", resp.Data["public_key"])
+	}
+	if strings.Contains(resp.Data["public_key"].(string), "\r\n") {
+		t.Fatal("expected the armored public key to use LF line endings by default")
+	}
+
+	req.Operation = logical.ReadOperation
+	req.Data = map[string]interface{}{"line_ending": "cr"}
+	if resp, _ = b.HandleRequest(context.Background(), req); !resp.IsError() {
+		t.Fatal("expected to fail, cr is not a supported line ending")
+	}
+	req.Data["line_ending"] = "crlf"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyCRLF := resp.Data["public_key"].(string)
+	if strings.Count(publicKeyCRLF, "\r\n") != strings.Count(publicKeyCRLF, "\n") {
+		t.Fatalf("expected every line of the armored public key to end with CRLF, got %q", publicKeyCRLF)
+	}
+	el, err = openpgp.ReadArmoredKeyRing(strings.NewReader(publicKeyCRLF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint := hex.EncodeToString(el[0].PrimaryKey.Fingerprint[:]); fingerprint != resp.Data["fingerprint"] {
+		t.Fatalf("expected the public key to have the fingerprint %s, got %s", resp.Data["fingerprint"], fingerprint)
+	}
+}
+
+func TestGPG_ReadKeyTransferablePublicKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	handle := func(operation logical.Operation, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      "keys/test",
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	handle(logical.UpdateOperation, map[string]interface{}{
+		"algorithm": "eddsa",
+		"identities": []interface{}{
+			map[string]interface{}{"real_name": "John Doe", "email": "john@example.com", "comment": "personal"},
+			map[string]interface{}{"real_name": "John Doe", "email": "john.doe@work.example.com"},
+			map[string]interface{}{"real_name": "Jane Doe", "email": "jane@example.com"},
+		},
+	})
+
+	publicKey := handle(logical.ReadOperation, map[string]interface{}{"export_format": "base64"}).Data["public_key"]
+	for i := 0; i < 10; i++ {
+		if other := handle(logical.ReadOperation, map[string]interface{}{"export_format": "base64"}).Data["public_key"]; other != publicKey {
+			t.Fatal("expected the public key to always be serialized in the same order")
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(publicKey.(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	packets := packet.NewReader(bytes.NewReader(raw))
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch p := p.(type) {
+		case *packet.PublicKey:
+			if p.IsSubkey {
+				order = append(order, "subkey")
+			} else {
+				order = append(order, "primary")
+			}
+		case *packet.UserId:
+			order = append(order, p.Id)
+		case *packet.Signature:
+			order = append(order, "signature")
+		}
+	}
+	expected := []string{
+		"primary",
+		"John Doe (personal) <john@example.com>", "signature",
+		"Jane Doe <jane@example.com>", "signature",
+		"John Doe <john.doe@work.example.com>", "signature",
+		"subkey", "signature",
+	}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("expected the packets %#v, got %#v", expected, order)
+	}
+
+	resp := handle(logical.ReadOperation, map[string]interface{}{"include_subkeys": false})
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(el[0].Subkeys) != 0 {
+		t.Fatalf("expected the public key to not include subkeys, got %d", len(el[0].Subkeys))
+	}
+	if len(el[0].Identities) != 3 {
+		t.Fatalf("expected the public key to include the 3 identities, got %d", len(el[0].Identities))
+	}
+}
+
+func TestGPG_ImportWeakKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	entity, err := openpgp.NewEntity("Legacy partner", "", "", &packet.Config{Algorithm: packet.PubKeyAlgoRSA, RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	weakKey := buf.String()
+
+	if resp := handle("keys/strong", map[string]interface{}{"generate": false, "key": gpgKey}); resp.IsError() || len(resp.Warnings) != 0 {
+		t.Fatalf("expected no warning for a 2048-bit RSA key, got %#v", resp)
+	}
+
+	resp := handle("keys/weak", map[string]interface{}{"generate": false, "key": weakKey, "allow_public_only": true})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	subkeyFingerprint := hex.EncodeToString(entity.Subkeys[0].PublicKey.Fingerprint)
+	expected := []string{
+		"the primary key is a 1024-bit RSA key, smaller than the min_rsa_bits configuration of 2048 bits",
+		"the subkey " + subkeyFingerprint + " is a 1024-bit RSA key, smaller than the min_rsa_bits configuration of 2048 bits",
+	}
+	if !reflect.DeepEqual(resp.Warnings, expected) {
+		t.Fatalf("expected the warnings %#v, got %#v", expected, resp.Warnings)
+	}
+
+	handle("config", map[string]interface{}{"min_rsa_bits": 1024, "allowed_algorithms": "eddsa"})
+	resp = handle("keys/preview", map[string]interface{}{"generate": false, "key": weakKey, "allow_public_only": true, "preview": true})
+	expected = []string{
+		"the primary key uses the rsa algorithm, not allowed by the allowed_algorithms configuration",
+		"the primary key is a 1024-bit RSA key, RSA keys smaller than 2048 bits are deprecated",
+		"the subkey " + subkeyFingerprint + " uses the rsa algorithm, not allowed by the allowed_algorithms configuration",
+		"the subkey " + subkeyFingerprint + " is a 1024-bit RSA key, RSA keys smaller than 2048 bits are deprecated",
+	}
+	if !reflect.DeepEqual(resp.Warnings, expected) {
+		t.Fatalf("expected the warnings %#v, got %#v", expected, resp.Warnings)
+	}
+}
+
+func TestGPG_ImportSubkeyFlags(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	importKey := func(name string, data map[string]interface{}) *logical.Response {
+		request := map[string]interface{}{"generate": false, "key": gpgKey}
+		for field, value := range data {
+			request[field] = value
+		}
+		return handle(logical.UpdateOperation, "keys/"+name, request)
+	}
+	capabilities := func(name string) []string {
+		return handle(logical.ReadOperation, "keys/"+name, nil).Data["subkeys"].([]map[string]interface{})[0]["capabilities"].([]string)
+	}
+
+	importKey("original", nil)
+	read := handle(logical.ReadOperation, "keys/original", nil)
+	subkey := read.Data["subkeys"].([]map[string]interface{})[0]["fingerprint"].(string)
+	if got := capabilities("original"); !reflect.DeepEqual(got, []string{"encrypt"}) {
+		t.Fatalf("expected an encryption subkey, got %#v", got)
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"the subkey does not exist":    {"subkey_flags": map[string]interface{}{"0011223344556677889900112233445566778899": "sign"}},
+		"certify is not a subkey flag": {"subkey_flags": map[string]interface{}{subkey: "certify"}},
+		"the private key is required":  {"subkey_flags": map[string]interface{}{subkey: "sign"}, "key": read.Data["public_key"], "allow_public_only": true},
+	}
+	for reason, data := range invalid {
+		if resp := importKey("invalid", data); !resp.IsError() {
+			t.Fatalf("expected to fail, %s", reason)
+		}
+	}
+
+	if resp := importKey("original", map[string]interface{}{"subkey_flags": map[string]interface{}{subkey: "authenticate"}, "force": true}); resp.IsError() || resp.Data["no_change"] != false {
+		t.Fatalf("expected the subkey flags to be changed, got %#v", resp)
+	}
+	if got := capabilities("original"); !reflect.DeepEqual(got, []string{"authenticate"}) {
+		t.Fatalf("expected an authentication-only subkey, got %#v", got)
+	}
+	if resp := handle(logical.UpdateOperation, "encrypt/original", map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA=="}); !resp.IsError() {
+		t.Fatal("expected to fail, the subkey can no longer encrypt")
+	}
+
+	if resp := importKey("signing", map[string]interface{}{"subkey_flags": map[string]interface{}{subkey: "sign, encrypt"}}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if got := capabilities("signing"); !reflect.DeepEqual(got, []string{"sign", "encrypt"}) {
+		t.Fatalf("expected a signing and encryption subkey, got %#v", got)
+	}
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	resp := handle(logical.UpdateOperation, "sign/signing", map[string]interface{}{"input": input, "subkey_fingerprint": subkey})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	verify := handle(logical.UpdateOperation, "verify/signing", map[string]interface{}{"input": input, "signature": resp.Data["signature"]})
+	if verify.IsError() || verify.Data["valid"] != true || verify.Data["signer_fingerprint"] != subkey {
+		t.Fatalf("expected the signature of the subkey to be valid, got %#v", verify)
+	}
+}
+
+func TestGPG_ImportPublicOnlyKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/signer", map[string]interface{}{"real_name": "Vault GPG test", "algorithm": "eddsa"})
+	publicKey := handle(logical.ReadOperation, "keys/signer", nil).Data["public_key"].(string)
+
+	if resp := handle(logical.UpdateOperation, "keys/public", map[string]interface{}{
+		"generate":          false,
+		"key":               publicKey,
+		"allow_public_only": true,
+		"passphrase":        "passphrase",
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, a public key cannot be protected by a passphrase")
+	}
+	if resp := handle(logical.UpdateOperation, "keys/public", map[string]interface{}{
+		"generate":          false,
+		"key":               publicKey,
+		"allow_public_only": true,
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	for name, expected := range map[string]bool{"signer": true, "public": false} {
+		if hasPrivateKey := handle(logical.ReadOperation, "keys/"+name, nil).Data["has_private_key"]; hasPrivateKey != expected {
+			t.Fatalf("expected has_private_key to be %t for key %s, got %v", expected, name, hasPrivateKey)
+		}
+	}
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	signature := handle(logical.UpdateOperation, "sign/signer", map[string]interface{}{"input": input}).Data["signature"]
+	resp := handle(logical.UpdateOperation, "verify/public", map[string]interface{}{"input": input, "signature": signature})
+	if resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected the signature to be valid with the public key, got %#v", resp)
+	}
+
+	resp = handle(logical.UpdateOperation, "encrypt/public", map[string]interface{}{"plaintext": input})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	ciphertext := resp.Data["ciphertext"]
+	if resp = handle(logical.UpdateOperation, "decrypt/signer", map[string]interface{}{"ciphertext": ciphertext, "format": "ascii-armor"}); resp.IsError() || resp.Data["plaintext"] != input {
+		t.Fatalf("expected the message encrypted with the public key to be decrypted, got %#v", resp)
+	}
+
+	if resp = handle(logical.UpdateOperation, "sign/public", map[string]interface{}{"input": input}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not have a private key")
+	}
+	if resp = handle(logical.UpdateOperation, "decrypt/public", map[string]interface{}{"ciphertext": ciphertext, "format": "ascii-armor"}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not have a private key")
+	}
+}
+
+func TestGPG_CreateKeyPreview(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data: map[string]interface{}{
+			"allow_seeded_keys": true,
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+			"seed":      "000102030405060708090a0b0c0d0e0f",
+			"preview":   true,
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if el[0].PrivateKey != nil {
+		t.Fatal("the preview must not contain the private key")
+	}
+	previewFingerprint := resp.Data["fingerprint"].(string)
+	previewPublicKey := resp.Data["public_key"]
+	if previewFingerprint != hex.EncodeToString(el[0].PrimaryKey.Fingerprint[:]) {
+		t.Fatalf("the fingerprint %s does not match the public key", previewFingerprint)
+	}
+	entry, err := b.key(context.Background(), storage, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatal("the previewed key must not be stored")
+	}
+
+	delete(req.Data, "preview")
+	if _, err = b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["fingerprint"] != previewFingerprint {
+		t.Fatalf("expected the stored key to have the previewed fingerprint %s, got %s", previewFingerprint, resp.Data["fingerprint"])
+	}
+	if resp.Data["public_key"] != previewPublicKey {
+		t.Fatalf("expected the stored public key to be the previewed one, got %s", resp.Data["public_key"])
+	}
+
+	// A preview does not require force when the key already exists
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+			"seed":      "000102030405060708090a0b0c0d0e0f",
+			"preview":   true,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("not expected error response: %#v, %v", resp, err)
+	}
+	if resp.Data["public_key"] != previewPublicKey {
+		t.Fatalf("expected the same preview, got %s", resp.Data["public_key"])
+	}
+}
+
+func TestGPG_CreateKeyPrimaryFlags(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name":     "Vault GPG test",
+		"algorithm":     "eddsa",
+		"primary_flags": "sign",
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, the primary key must have the certify flag")
+	}
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name":     "Vault GPG test",
+		"algorithm":     "eddsa",
+		"primary_flags": "certify,encrypt",
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, the encrypt flag is not supported")
+	}
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"algorithm":     "eddsa",
+		"primary_flags": []string{"certify"},
+		"identities": []interface{}{
+			map[string]interface{}{"real_name": "Vault GPG test"},
+			map[string]interface{}{"real_name": "Vault GPG test", "comment": "root"},
+		},
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	readCapabilities := func() interface{} {
+		return handle(logical.ReadOperation, "keys/test", nil).Data["capabilities"]
+	}
+	if capabilities := readCapabilities(); !reflect.DeepEqual(capabilities, []string{"certify"}) {
+		t.Fatalf("expected a certify-only primary key, got %#v", capabilities)
+	}
+	if resp := handle(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+	}); resp == nil || !resp.IsError() {
+		t.Fatal("expected to fail, the key cannot sign")
+	}
+
+	handle(logical.UpdateOperation, "rotate/test", nil)
+	if capabilities := readCapabilities(); !reflect.DeepEqual(capabilities, []string{"certify"}) {
+		t.Fatalf("expected the rotated primary key to stay certify-only, got %#v", capabilities)
+	}
+}
+
+func TestGPG_CreateKeyPreferences(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	invalid := map[string]string{
+		"preferred_ciphers":     "aes256,cast5",
+		"preferred_hashes":      "sha1",
+		"preferred_compression": "bzip2",
+	}
+	for field, value := range invalid {
+		if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+			field:       value,
+		}); !resp.IsError() {
+			t.Fatalf("expected to fail, %s is not supported in %s", value, field)
+		}
+	}
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name":             "Vault GPG test",
+		"algorithm":             "eddsa",
+		"preferred_ciphers":     "aes256,aes128",
+		"preferred_hashes":      []string{"sha2-512", "sha2-256"},
+		"preferred_compression": "zlib,none",
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	checkPreferences := func() {
+		keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(handle(logical.ReadOperation, "keys/test", nil).Data["public_key"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		selfSignature, _ := keyring[0].PrimarySelfSignature()
+		if expected := []uint8{uint8(packet.CipherAES256), uint8(packet.CipherAES128)}; !reflect.DeepEqual(selfSignature.PreferredSymmetric, expected) {
+			t.Fatalf("expected preferred ciphers %v, got %v", expected, selfSignature.PreferredSymmetric)
+		}
+		if expected := []uint8{10, 8}; !reflect.DeepEqual(selfSignature.PreferredHash, expected) {
+			t.Fatalf("expected preferred hashes %v, got %v", expected, selfSignature.PreferredHash)
+		}
+		if expected := []uint8{uint8(packet.CompressionZLIB), uint8(packet.CompressionNone)}; !reflect.DeepEqual(selfSignature.PreferredCompression, expected) {
+			t.Fatalf("expected preferred compression %v, got %v", expected, selfSignature.PreferredCompression)
+		}
+	}
+	checkPreferences()
+
+	handle(logical.UpdateOperation, "rotate/test", nil)
+	checkPreferences()
+}
+
+func TestGPG_CreateKeyCurve(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	invalid := map[string]string{
+		"rsa":   "nistp256",
+		"ecdsa": "curve25519",
+		"eddsa": "nistp384",
+	}
+	for algorithm, curve := range invalid {
+		if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": algorithm,
+			"curve":     curve,
+		}); !resp.IsError() {
+			t.Fatalf("expected to fail, the curve %s cannot be used with %s", curve, algorithm)
+		}
+	}
+
+	strengths := map[string]string{
+		"nistp384":   "ECDSA-P384",
+		"nistp521":   "ECDSA-P521",
+		"curve25519": "Ed25519",
+	}
+	for curve, strength := range strengths {
+		algorithm := "ecdsa"
+		if curve == "curve25519" {
+			algorithm = "eddsa"
+		}
+		if resp := handle(logical.UpdateOperation, "keys/"+curve, map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": algorithm,
+			"curve":     curve,
+		}); resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		if got := handle(logical.ReadOperation, "keys/"+curve, nil).Data["strength"]; got != strength {
+			t.Fatalf("expected strength %s, got %s", strength, got)
+		}
+	}
+
+	handle(logical.UpdateOperation, "rotate/nistp384", nil)
+	if got := handle(logical.ReadOperation, "keys/nistp384", nil).Data["strength"]; got != "ECDSA-P384" {
+		t.Fatalf("expected the rotated key to keep its curve, got %s", got)
+	}
+}
+
+func TestGPG_CreateKeyWithoutEncryptionSubkey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name":            "Vault GPG test",
+		"algorithm":            "eddsa",
+		"no_encryption_subkey": true,
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	checkSubkeys := func() {
+		read := handle(logical.ReadOperation, "keys/test", nil)
+		if subkeys := read.Data["subkeys"].([]map[string]interface{}); len(subkeys) != 0 {
+			t.Fatalf("expected no subkey, got %#v", subkeys)
+		}
+		if fingerprint := read.Data["encryption_subkey_fingerprint"]; fingerprint != nil {
+			t.Fatalf("expected no encryption subkey fingerprint, got %#v", fingerprint)
+		}
+	}
+	checkSubkeys()
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	if resp := handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{"plaintext": input}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not have an encryption subkey")
+	}
+	if resp := handle(logical.UpdateOperation, "sign/test", map[string]interface{}{"input": input}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	handle(logical.UpdateOperation, "rotate/test", nil)
+	checkSubkeys()
+}
+
+func TestGPG_CreateKeyMixedAlgorithms(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	invalidSubkeys := [][]interface{}{
+		{"eddsa"},
+		{map[string]interface{}{"usage": "authenticate"}},
+		{map[string]interface{}{"algorithm": "dsa"}},
+		{map[string]interface{}{"algorithm": "rsa", "curve": "nistp256"}},
+		{map[string]interface{}{"algorithm": "rsa", "key_bits": 1024}},
+		{map[string]interface{}{"algorithm": "eddsa", "size": 256}},
+	}
+	for _, subkeys := range invalidSubkeys {
+		if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{"algorithm": "eddsa", "real_name": "Vault GPG test", "subkeys": subkeys}); !resp.IsError() {
+			t.Fatalf("expected to fail with subkeys %#v", subkeys)
+		}
+	}
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"algorithm":            "eddsa",
+		"real_name":            "Vault GPG test",
+		"no_encryption_subkey": true,
+		"subkeys":              []interface{}{map[string]interface{}{"usage": "sign"}},
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, subkeys cannot be used with no_encryption_subkey")
+	}
+
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"algorithm": "rsa",
+		"real_name": "Vault GPG test",
+		"subkeys": []interface{}{
+			map[string]interface{}{"usage": "sign", "algorithm": "eddsa"},
+			map[string]interface{}{"algorithm": "ecdsa", "curve": "nistp384"},
+		},
+	}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	read := handle(logical.ReadOperation, "keys/test", nil)
+	if read.Data["strength"] != "RSA-2048" {
+		t.Fatalf("expected an RSA-2048 primary key, got %s", read.Data["strength"])
+	}
+	subkeys := read.Data["subkeys"].([]map[string]interface{})
+	if len(subkeys) != 2 {
+		t.Fatalf("expected 2 subkeys, got %#v", subkeys)
+	}
+	expected := []map[string]interface{}{
+		{"algorithm": "eddsa", "strength": "Ed25519", "capabilities": []string{"sign"}},
+		{"algorithm": "ecdh", "strength": "ECDH-P384", "capabilities": []string{"encrypt"}},
+	}
+	for i, subkey := range subkeys {
+		for field, value := range expected[i] {
+			if !reflect.DeepEqual(subkey[field], value) {
+				t.Fatalf("expected subkey %d to have the %s %v, got %v", i, field, value, subkey[field])
+			}
+		}
+	}
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	signature := handle(logical.UpdateOperation, "sign/test", map[string]interface{}{"input": input, "subkey_fingerprint": subkeys[0]["fingerprint"]}).Data["signature"]
+	if resp := handle(logical.UpdateOperation, "verify/test", map[string]interface{}{"input": input, "signature": signature}); !resp.Data["valid"].(bool) || resp.Data["signer_fingerprint"] != subkeys[0]["fingerprint"] {
+		t.Fatalf("expected a valid signature of the eddsa subkey, got %#v", resp.Data)
+	}
+	ciphertext := handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{"plaintext": input, "format": "base64"}).Data["ciphertext"]
+	if resp := handle(logical.UpdateOperation, "decrypt/test", map[string]interface{}{"ciphertext": ciphertext}); resp.IsError() || resp.Data["plaintext"] != input {
+		t.Fatalf("expected the message encrypted for the ecdh subkey to be decrypted, got %#v", resp)
+	}
+}
+
+func TestGPG_ReadKeySSHFormat(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	readSSHKey := func(name string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"export_format": "ssh",
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	tests := map[string]string{
+		"rsa":   "ssh-rsa",
+		"ecdsa": "ecdsa-sha2-nistp256",
+		"eddsa": "ssh-ed25519",
+	}
+	for algorithm, sshType := range tests {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + algorithm,
+			Data: map[string]interface{}{
+				"real_name":     "Vault GPG test",
+				"algorithm":     algorithm,
+				"primary_flags": "certify,authenticate",
+			},
+		}
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := readSSHKey(algorithm)
+		if resp.IsError() {
+			t.Fatalf("not expected error response for %s: %#v", algorithm, *resp)
+		}
+		sshKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(resp.Data["public_key"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sshKey.Type() != sshType {
+			t.Fatalf("expected a %s key for %s, got %s", sshType, algorithm, sshKey.Type())
+		}
+		fingerprint := resp.Data["fingerprint"].(string)
+		if expected := "openpgp:0x" + strings.ToUpper(fingerprint[len(fingerprint)-8:]); comment != expected {
+			t.Fatalf("expected comment %s, got %s", expected, comment)
+		}
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if resp := readSSHKey("test"); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not have an authentication key")
+	}
+}
+
+func TestGPG_ImportKeyRingSelectFingerprint(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entities []*openpgp.Entity
+	for _, name := range []string{"First", "Second"} {
+		entity, err := openpgp.NewEntity(name, "", "", config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = entity.SerializePrivate(w, nil); err != nil {
+			t.Fatal(err)
+		}
+		entities = append(entities, entity)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	importKey := func(name string, selectFingerprint string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"generate":           false,
+				"key":                buf.String(),
+				"select_fingerprint": selectFingerprint,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := importKey("test", ""); !resp.IsError() {
+		t.Fatal("expected to fail, the key ring contains several keys")
+	}
+	if resp := importKey("test", "0000000000000000000000000000000000000000"); !resp.IsError() {
+		t.Fatal("expected to fail, no key matches the fingerprint")
+	}
+
+	second := entities[1]
+	tests := map[string]string{
+		"primary": strings.ToUpper(hex.EncodeToString(second.PrimaryKey.Fingerprint)),
+		"subkey":  hex.EncodeToString(second.Subkeys[0].PublicKey.Fingerprint),
+	}
+	for name, fingerprint := range tests {
+		if resp := importKey(name, fingerprint); resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		entry, err := b.key(context.Background(), storage, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entity, err := b.entity(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(entity.PrimaryKey.Fingerprint, second.PrimaryKey.Fingerprint) {
+			t.Fatalf("expected the second key to be imported with the %s fingerprint, got %x", name, entity.PrimaryKey.Fingerprint)
+		}
+	}
+}
+
+func TestGPG_ImportPassphraseProtectedKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	b := Backend()
+
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.EncryptPrivateKeys([]byte("import"), nil); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	resp := handle("keys/test", map[string]interface{}{"generate": false, "key": buf.String()})
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "import_passphrase") {
+		t.Fatalf("expected to fail, the key is protected by a passphrase, got %#v", resp)
+	}
+	if resp = handle("keys/test", map[string]interface{}{"generate": false, "key": buf.String(), "import_passphrase": "wrong"}); !resp.IsError() {
+		t.Fatal("expected to fail, the import passphrase is wrong")
+	}
+
+	if resp = handle("keys/unprotected", map[string]interface{}{"generate": false, "key": buf.String(), "import_passphrase": "import"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp = handle("keys/protected", map[string]interface{}{"generate": false, "key": buf.String(), "import_passphrase": "import", "passphrase": "vault"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	if resp = handle("sign/unprotected", map[string]interface{}{"input": input}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp = handle("sign/protected", map[string]interface{}{"input": input, "passphrase": "import"}); !resp.IsError() {
+		t.Fatal("expected to fail, the key is protected by the Vault passphrase")
+	}
+	if resp = handle("sign/protected", map[string]interface{}{"input": input, "passphrase": "vault"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+}
+
+func TestGPG_ImportBinaryKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	importKey := func(data map[string]interface{}) *logical.Response {
+		data["generate"] = false
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/test",
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	block, err := armor.Decode(strings.NewReader(gpgKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var binaryKey bytes.Buffer
+	if _, err = binaryKey.ReadFrom(block.Body); err != nil {
+		t.Fatal(err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(binaryKey.Bytes())
+
+	if resp := importKey(map[string]interface{}{"key": encodedKey}); !resp.IsError() {
+		t.Fatal("expected to fail, the key is not ASCII-armored")
+	}
+	if resp := importKey(map[string]interface{}{"key": gpgKey, "input_format": "binary"}); !resp.IsError() {
+		t.Fatal("expected to fail, the key is not base64-encoded")
+	}
+	if resp := importKey(map[string]interface{}{"key": encodedKey, "input_format": "pem"}); !resp.IsError() {
+		t.Fatal("expected to fail, pem is not a supported input format")
+	}
+	if resp := importKey(map[string]interface{}{"key": encodedKey, "input_format": "binary", "preview": true}); resp.IsError() || resp.Data["fingerprint"] != "fbbc9a77bb696e6787ef0b5b2f7b5633b6f42527" {
+		t.Fatalf("expected the binary key to be imported, got %#v", resp)
+	}
+	if resp := importKey(map[string]interface{}{"key": encodedKey, "input_format": "binary"}); resp.IsError() || resp.Data["fingerprint"] != "fbbc9a77bb696e6787ef0b5b2f7b5633b6f42527" {
+		t.Fatalf("expected the fingerprint of the stored key to be returned, got %#v", resp)
+	}
+}
+
+func TestGPG_KeyFolders(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil && (resp == nil || !resp.IsError()) {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, name := range []string{"flat", "team-a/signing", "team-a/sub/encryption", "team-b/signing"} {
+		if resp := handle(logical.UpdateOperation, "keys/"+name, map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		}); resp != nil && resp.IsError() {
+			t.Fatalf("not expected error response for %s: %#v", name, *resp)
+		}
+	}
+
+	lists := map[string]interface{}{
+		"keys/":        []string{"flat", "team-a/signing", "team-a/sub/encryption", "team-b/signing"},
+		"keys/team-a/": []string{"signing", "sub/encryption"},
+		"keys/team-c/": nil,
+	}
+	for path, expected := range lists {
+		if keys := handle(logical.ListOperation, path, nil).Data["keys"]; !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("expected keys %#v for %s, got %#v", expected, path, keys)
+		}
+	}
+	resp := handle(logical.ListOperation, "keys/team-a/", map[string]interface{}{"detailed": true})
+	if _, ok := resp.Data["key_info"].(map[string]interface{})["sub/encryption"]; !ok {
+		t.Fatalf("expected the details of the keys of the subfolder, got %#v", resp.Data["key_info"])
+	}
+
+	fingerprint := handle(logical.ReadOperation, "keys/team-a/signing", nil).Data["fingerprint"]
+	if fingerprint == handle(logical.ReadOperation, "keys/team-b/signing", nil).Data["fingerprint"] {
+		t.Fatal("expected the keys of different folders to be different")
+	}
+	if name := handle(logical.ReadOperation, "keys/by-fingerprint/"+fingerprint.(string), nil).Data["name"]; name != "team-a/signing" {
+		t.Fatalf("expected the key found by fingerprint to be team-a/signing, got %#v", name)
+	}
+	if resp = handle(logical.ReadOperation, "keys/team-a/signing/health", nil); resp.Data["healthy"] != true {
+		t.Fatalf("expected the key health to be reported, got %#v", resp.Data)
+	}
+
+	resp = handle(logical.UpdateOperation, "sign/team-a/signing/sha2-512", map[string]interface{}{
+		"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+	})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp = handle(logical.UpdateOperation, "verify/team-a/signing", map[string]interface{}{
+		"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		"signature": resp.Data["signature"],
+	}); resp.Data["valid"] != true || resp.Data["hash_algorithm"] != "sha2-512" {
+		t.Fatalf("expected a valid sha2-512 signature, got %#v", resp.Data)
+	}
+
+	handle(logical.DeleteOperation, "keys/team-a/signing", nil)
+	if keys := handle(logical.ListOperation, "keys/team-a/", nil).Data["keys"]; !reflect.DeepEqual(keys, []string{"sub/encryption"}) {
+		t.Fatalf("expected the deleted key to not be listed, got %#v", keys)
+	}
+}
+
+func TestGPG_DeleteKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	resp := handle(logical.DeleteOperation, "keys/notfound", nil)
+	if resp.IsError() || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "does not exist") {
+		t.Fatalf("expected a warning when deleting a key that does not exist, got %#v", resp)
+	}
+
+	handle(logical.UpdateOperation, "config", map[string]interface{}{"require_delete_confirmation": true})
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	if resp = handle(logical.DeleteOperation, "keys/test", nil); !resp.IsError() {
+		t.Fatal("expected to fail, the deletion is not confirmed")
+	}
+	if resp = handle(logical.ReadOperation, "keys/test", nil); resp == nil || resp.IsError() {
+		t.Fatal("expected the key to not be deleted")
+	}
+
+	resp = handle(logical.DeleteOperation, "keys/test", map[string]interface{}{"confirm": true})
+	if resp.IsError() || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "can no longer be verified") {
+		t.Fatalf("expected a warning about the signatures that can no longer be verified, got %#v", resp)
+	}
+	if resp = handle(logical.ReadOperation, "keys/test", nil); !resp.IsError() {
+		t.Fatalf("expected the key to be deleted, got %#v", resp)
+	}
+}
+
+func TestGPG_GenerateEntityContextDone(t *testing.T) {
+	identities := []identity{{realName: "Vault GPG test"}}
+	config, err := keyConfig("rsa", "", 4096, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = generateEntityContext(ctx, identities, []string{"certify", "sign"}, keyPreferences{}, config, nil); err == nil {
+		t.Fatal("expected to fail, the context is done")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err = generateEntityContext(ctx, identities, []string{"certify", "sign"}, keyPreferences{}, config, nil); err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Fatalf("expected to fail, the context timed out before the key was generated, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected to return when the context timed out, returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,110 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRevoke(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "revoke/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"reason": {
+				Type:        framework.TypeString,
+				Default:     "no_reason",
+				Description: "The reason for revocation: compromised, superseded, retired or no_reason.",
+			},
+			"reason_text": {
+				Type:        framework.TypeString,
+				Description: "A human readable explanation of the revocation.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathRevokeRead,
+			},
+		},
+		HelpSynopsis:    pathRevokeHelpSyn,
+		HelpDescription: pathRevokeHelpDesc,
+	}
+}
+
+func (b *backend) pathRevokeRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var reason packet.ReasonForRevocation
+	switch data.Get("reason").(string) {
+	case "compromised":
+		reason = packet.KeyCompromised
+	case "superseded":
+		reason = packet.KeySuperseded
+	case "retired":
+		reason = packet.KeyRetired
+	case "no_reason":
+		reason = packet.NoReason
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported revocation reason %s; must be compromised, superseded, retired or no_reason", data.Get("reason").(string))), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err = decryptPrivateKeys(entity, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if err = entity.RevokeKey(reason, data.Get("reason_text").(string), nil); err != nil {
+		return nil, err
+	}
+	revocation := entity.Revocations[len(entity.Revocations)-1]
+
+	var buf bytes.Buffer
+	headers := map[string]string{
+		"Comment": "This is a revocation certificate",
+	}
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, headers)
+	if err != nil {
+		return nil, err
+	}
+	if err = revocation.Serialize(w); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"revocation_certificate": buf.String(),
+		},
+	}, nil
+}
+
+const pathRevokeHelpSyn = "Generate a revocation certificate for a named GPG key"
+
+const pathRevokeHelpDesc = `
+This path generates an ASCII-armored revocation certificate for the primary
+key of the named GPG key. The key itself is left untouched in storage; the
+certificate is only returned so that it can be distributed when the key has
+to be revoked.
+`
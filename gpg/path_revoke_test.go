@@ -0,0 +1,105 @@
+package gpg
+
+import (
+	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"strings"
+	"testing"
+)
+
+func TestGPG_Revoke(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqRevoke := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "revoke/test",
+		Data: map[string]interface{}{
+			"reason":      "compromised",
+			"reason_text": "Key leaked",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), reqRevoke)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	block, err := armor.Decode(strings.NewReader(resp.Data["revocation_certificate"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		t.Fatalf("expected a signature packet, got %T", p)
+	}
+	if sig.SigType != packet.SigTypeKeyRevocation {
+		t.Fatalf("expected a key revocation signature, got %d", sig.SigType)
+	}
+	if sig.RevocationReason == nil || *sig.RevocationReason != packet.KeyCompromised || sig.RevocationReasonText != "Key leaked" {
+		t.Fatal("revocation reason does not match")
+	}
+
+	reqRead := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err = b.HandleRequest(context.Background(), reqRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil {
+		t.Fatal("the key must not be deleted")
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = el[0].PrimaryKey.VerifyRevocationSignature(sig); err != nil {
+		t.Fatalf("revocation certificate is not valid: %s", err)
+	}
+	if len(el[0].Revocations) != 0 {
+		t.Fatal("the stored key must not be revoked")
+	}
+
+	revokeMustFail := func(keyName, reason string) {
+		reqRevoke := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "revoke/" + keyName,
+			Data: map[string]interface{}{
+				"reason": reason,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), reqRevoke)
+		if !resp.IsError() {
+			t.Fatalf("expected to fail, keyname: %s, reason: %s", keyName, reason)
+		}
+	}
+
+	revokeMustFail("doNotExist", "no_reason")
+	revokeMustFail("test", "unknown")
+}
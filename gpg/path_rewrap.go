@@ -0,0 +1,152 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+	"strings"
+	"time"
+)
+
+func pathRewrap(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rewrap/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+			"ciphertext": {
+				Type:        framework.TypeString,
+				Description: "The ciphertext to re-encrypt",
+			},
+			"format": {
+				Type:        framework.TypeString,
+				Default:     "base64",
+				Description: `Encoding format the ciphertext uses, the re-encrypted ciphertext uses the same. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: withMetrics("rewrap", b.withUsageTracking(b.pathRewrapWrite)),
+			},
+		},
+		HelpSynopsis:    pathRewrapHelpSyn,
+		HelpDescription: pathRewrapHelpDesc,
+	}
+}
+
+func (b *backend) pathRewrapWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if resp, err := b.checkInputSize(ctx, req.Storage, data.Get("ciphertext").(string)); resp != nil || err != nil {
+		return resp, err
+	}
+	format := data.Get("format").(string)
+	switch format {
+	case "base64":
+	case "ascii-armor":
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	markKeyUsed(ctx, data.Get("name").(string))
+	if err = entry.checkUsable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if resp, err := b.checkRateLimit(data.Get("name").(string), entry, 1); resp != nil || err != nil {
+		return resp, err
+	}
+	keyring, err := b.keyring(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err = decryptKeyringPrivateKeys(keyring, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// The message is encrypted again for the current version of the key, the previous versions are only
+	// used to decrypt it.
+	current := keyring[0]
+	if !canEncrypt(current) {
+		return logical.ErrorResponse("no encryption key available, the key does not have an encryption capable key or subkey"), logical.ErrInvalidRequest
+	}
+	if err = checkExpiration(current, time.Now(), false); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	encryptionKey, ok := current.EncryptionKey(time.Now())
+	if !ok {
+		return logical.ErrorResponse("the key does not have a valid encryption key or subkey"), logical.ErrInvalidRequest
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	message, err := decrypt(keyring, data.Get("ciphertext").(string), format, false, false, "", config.MaxOutputBytes)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	var ciphertext bytes.Buffer
+	var ciphertextEncoder io.WriteCloser
+	if format == "base64" {
+		ciphertextEncoder = base64.NewEncoder(base64.StdEncoding, &ciphertext)
+	} else {
+		ciphertextEncoder, err = armor.Encode(&ciphertext, "PGP MESSAGE", nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	w, err := openpgp.Encrypt(ciphertextEncoder, []*openpgp.Entity{current}, nil, &openpgp.FileHints{IsBinary: true}, &packet.Config{
+		DefaultCompressionAlgo: packet.CompressionZLIB,
+		DefaultCipher:          packet.CipherAES256,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(w, base64.NewDecoder(base64.StdEncoding, strings.NewReader(message.plaintext))); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	if err = ciphertextEncoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"ciphertext":                 ciphertext.String(),
+			"key_fingerprint":            hex.EncodeToString(current.PrimaryKey.Fingerprint[:]),
+			"encryption_key_fingerprint": hex.EncodeToString(encryptionKey.PublicKey.Fingerprint),
+			"decryption_key_fingerprint": message.decryptionFingerprint,
+		},
+	}, nil
+}
+
+const pathRewrapHelpSyn = "Re-encrypt a ciphertext for the current version of a named GPG key"
+
+const pathRewrapHelpDesc = `
+This path decrypts the ciphertext with the named GPG key, including its
+previous versions, and encrypts it again for the current version of the key.
+The plaintext is never returned. A signature of the original message is not
+kept.
+`
@@ -0,0 +1,71 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"testing"
+)
+
+func TestGPG_Rewrap(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	plaintext := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	ciphertext := handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{
+		"plaintext": plaintext,
+		"format":    "base64",
+	}).Data["ciphertext"]
+	oldSubkey := handle(logical.ReadOperation, "keys/test", nil).Data["subkeys"].([]map[string]interface{})[0]["fingerprint"]
+
+	handle(logical.UpdateOperation, "rotate/test", nil)
+	read := handle(logical.ReadOperation, "keys/test", nil)
+	newSubkey := read.Data["subkeys"].([]map[string]interface{})[0]["fingerprint"].(string)
+
+	if resp := handle(logical.UpdateOperation, "rewrap/test", map[string]interface{}{"ciphertext": ciphertext, "format": "binary"}); !resp.IsError() {
+		t.Fatal("expected to fail, binary is not a supported format")
+	}
+	if resp := handle(logical.UpdateOperation, "rewrap/notfound", map[string]interface{}{"ciphertext": ciphertext}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not exist")
+	}
+	if resp := handle(logical.UpdateOperation, "rewrap/test", map[string]interface{}{"ciphertext": "Not base64 encoded"}); !resp.IsError() {
+		t.Fatal("expected to fail, the ciphertext is not base64 encoded")
+	}
+
+	resp := handle(logical.UpdateOperation, "rewrap/test", map[string]interface{}{"ciphertext": ciphertext})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if _, ok := resp.Data["plaintext"]; ok {
+		t.Fatal("the plaintext must not be returned")
+	}
+	if resp.Data["key_fingerprint"] != read.Data["fingerprint"] || resp.Data["encryption_key_fingerprint"] != newSubkey || resp.Data["decryption_key_fingerprint"] != oldSubkey {
+		t.Fatalf("expected the message to be encrypted again from %s to %s, got %#v", oldSubkey, newSubkey, resp.Data)
+	}
+
+	resp = handle(logical.UpdateOperation, "decrypt/test", map[string]interface{}{"ciphertext": resp.Data["ciphertext"]})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["plaintext"] != plaintext {
+		t.Fatal("the rewrapped message has not been decrypted to the plaintext")
+	}
+	if recipients := resp.Data["recipient_key_ids"]; !reflect.DeepEqual(recipients, []string{newSubkey[len(newSubkey)-16:]}) {
+		t.Fatalf("expected the rewrapped message to be encrypted for the new subkey only, got %#v", recipients)
+	}
+}
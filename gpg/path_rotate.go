@@ -0,0 +1,158 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRotate(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotate/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase used to encrypt the private key of the new version before it is stored. Required if the current version is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRotateWrite,
+			},
+		},
+		HelpSynopsis:    pathRotateHelpSyn,
+		HelpDescription: pathRotateHelpDesc,
+	}
+}
+
+func (b *backend) pathRotateWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(name)
+	}
+	if err = entry.checkKeyMaterialUpdatable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if entity.PrivateKey == nil {
+		return logical.ErrorResponse("keys without a private key cannot be rotated"), logical.ErrInvalidRequest
+	}
+	// The new version keeps the protection of the current one
+	passphrase := data.Get("passphrase").(string)
+	if passphrase == "" && privateKeysEncrypted(entity) {
+		return logical.ErrorResponse("the key is protected by a passphrase, a passphrase is required to protect the new version"), logical.ErrInvalidRequest
+	}
+
+	config, err := rotationConfig(entity)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	identities := []identity{}
+	for _, id := range entityIdentities(entity) {
+		identities = append(identities, identity{realName: id.UserId.Name, comment: id.UserId.Comment, email: id.UserId.Email})
+	}
+	if len(identities) == 0 {
+		identities = append(identities, identity{})
+	}
+	// The capabilities of the primary key are kept, unless they cannot be asserted by a generated key.
+	// The preferred algorithms are kept as well.
+	selfSignature, _ := entity.PrimarySelfSignature()
+	primaryFlags := []string{"certify", "sign"}
+	if validatePrimaryFlags(keyCapabilities(selfSignature)) == nil {
+		primaryFlags = keyCapabilities(selfSignature)
+	}
+	rotated, err := generateEntityContext(ctx, identities, primaryFlags, signaturePreferences(selfSignature), config, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, err
+	}
+	// Keys generated without an encryption subkey stay without one
+	if !canEncrypt(entity) {
+		rotated.Subkeys = nil
+	}
+	if passphrase != "" {
+		err = rotated.EncryptPrivateKeys([]byte(passphrase), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	err = serializePrivateWithoutSigning(&buf, rotated)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.PreviousKeys = append([][]byte{entry.SerializedKey}, entry.PreviousKeys...)
+	entry.SerializedKey = buf.Bytes()
+	if err := entry.setMetadata(rotated); err != nil {
+		return nil, err
+	}
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// rotationConfig returns the configuration to generate a key similar to the given entity.
+func rotationConfig(entity *openpgp.Entity) (*packet.Config, error) {
+	config := &packet.Config{
+		Algorithm:              entity.PrimaryKey.PubKeyAlgo,
+		DefaultCompressionAlgo: keyCompressionAlgo,
+	}
+	switch entity.PrimaryKey.PubKeyAlgo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly:
+		bitLength, err := entity.PrimaryKey.BitLength()
+		if err != nil {
+			return nil, err
+		}
+		config.Algorithm = packet.PubKeyAlgoRSA
+		config.RSABits = int(bitLength)
+	case packet.PubKeyAlgoECDSA, packet.PubKeyAlgoEdDSA:
+		curve, err := entity.PrimaryKey.Curve()
+		if err != nil {
+			return nil, err
+		}
+		config.Curve = curve
+	default:
+		return nil, fmt.Errorf("keys using the public key algorithm %d cannot be rotated", entity.PrimaryKey.PubKeyAlgo)
+	}
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if selfSignature != nil && selfSignature.KeyLifetimeSecs != nil {
+		config.KeyLifetimeSecs = *selfSignature.KeyLifetimeSecs
+	}
+	// AEAD support is only advertised if the key already advertises it
+	if selfSignature != nil && selfSignature.SEIPDv2 {
+		config.AEADConfig = &packet.AEADConfig{}
+	}
+	return config, nil
+}
+
+const pathRotateHelpSyn = "Rotate named GPG key"
+
+const pathRotateHelpDesc = `
+This path is used to generate a new version of the named GPG key using the
+same identity and algorithm. Previous versions are kept to verify signatures
+and decrypt messages made with them.
+`
@@ -0,0 +1,178 @@
+package gpg
+
+import (
+	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGPG_Rotate(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"email":     "vault@example.com",
+			"algorithm": "eddsa",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readKey := func() map[string]interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/test",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+	before := readKey()
+
+	reqSign := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Data: map[string]interface{}{
+			"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), reqSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := resp.Data["signature"]
+
+	reqRotate := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "rotate/test",
+	}
+	resp, err = b.HandleRequest(context.Background(), reqRotate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	after := readKey()
+	if after["fingerprint"] == before["fingerprint"] {
+		t.Fatal("the key has not been rotated")
+	}
+	if !reflect.DeepEqual(after["previous_fingerprints"], []string{before["fingerprint"].(string)}) {
+		t.Fatalf("expected previous fingerprints [%s], got %#v", before["fingerprint"], after["previous_fingerprints"])
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(after["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := el[0].Identities["Vault GPG test <vault@example.com>"]; !ok {
+		t.Fatalf("the identity of the key has not been kept: %#v", el[0].Identities)
+	}
+
+	reqVerify := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "verify/test",
+		Data: map[string]interface{}{
+			"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"signature": signature,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqVerify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data["valid"].(bool) {
+		t.Fatal("signature made with the previous version of the key is not valid")
+	}
+
+	reqRotate.Path = "rotate/doNotExist"
+	resp, _ = b.HandleRequest(context.Background(), reqRotate)
+	if !resp.IsError() {
+		t.Fatal("expected to fail, key does not exist")
+	}
+}
+
+func TestGPG_RotatePassphrase(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	encrypt := func() string {
+		resp := handle("encrypt/test", map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "format": "ascii-armor"})
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		return resp.Data["ciphertext"].(string)
+	}
+
+	handle("keys/test", map[string]interface{}{
+		"real_name":  "Vault GPG test",
+		"algorithm":  "eddsa",
+		"passphrase": "first",
+	})
+	firstCiphertext := encrypt()
+
+	if resp := handle("rotate/test", nil); !resp.IsError() {
+		t.Fatal("expected to fail, the new version must stay protected by a passphrase")
+	}
+	if resp := handle("rotate/test", map[string]interface{}{"passphrase": "second"}); resp != nil && resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	secondCiphertext := encrypt()
+
+	// Each version is unlocked by its own passphrase
+	for _, test := range []struct {
+		ciphertext string
+		passphrase string
+	}{
+		{firstCiphertext, "first"},
+		{secondCiphertext, "second"},
+	} {
+		resp := handle("decrypt/test", map[string]interface{}{"ciphertext": test.ciphertext, "format": "ascii-armor", "passphrase": test.passphrase})
+		if resp.IsError() || resp.Data["plaintext"] != "dGhlIHF1aWNrIGJyb3duIGZveA==" {
+			t.Fatalf("expected the message to be decrypted with the passphrase %s, got %#v", test.passphrase, resp)
+		}
+	}
+	if resp := handle("decrypt/test", map[string]interface{}{"ciphertext": firstCiphertext, "format": "ascii-armor", "passphrase": "second"}); !resp.IsError() {
+		t.Fatal("expected to fail, the previous version is protected by another passphrase")
+	}
+	if resp := handle("decrypt/test", map[string]interface{}{"ciphertext": firstCiphertext, "format": "ascii-armor", "passphrase": "wrong"}); !resp.IsError() {
+		t.Fatal("expected to fail, the passphrase does not unlock any version")
+	}
+	resp := handle("rewrap/test", map[string]interface{}{"ciphertext": firstCiphertext, "format": "ascii-armor", "passphrase": "first"})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	resp = handle("decrypt/test", map[string]interface{}{"ciphertext": resp.Data["ciphertext"], "format": "ascii-armor", "passphrase": "second"})
+	if resp.IsError() || resp.Data["plaintext"] != "dGhlIHF1aWNrIGJyb3duIGZveA==" {
+		t.Fatalf("expected the rewrapped message to be decrypted with the passphrase of the new version, got %#v", resp)
+	}
+}
@@ -0,0 +1,164 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+	"strings"
+	"time"
+)
+
+func pathSeal(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "seal/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to sign with",
+			},
+			"plaintext": {
+				Type:        framework.TypeString,
+				Description: "The base64-encoded plaintext to sign and encrypt",
+			},
+			"recipient_key": {
+				Type:        framework.TypeString,
+				Description: "The ASCII-armored GPG public key to encrypt for. If empty, the message is encrypted for the named key.",
+			},
+			"format": {
+				Type:        framework.TypeString,
+				Default:     "ascii-armor",
+				Description: `Encoding format to use. Can be "base64" or "ascii-armor". Defaults to "ascii-armor".`,
+			},
+			"aead": {
+				Type:        framework.TypeBool,
+				Description: "If true, the message is encrypted with AEAD in a version 2 symmetrically encrypted integrity protected data packet (RFC 9580). The recipient key must advertise support for it.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: withMetrics("seal", b.withUsageTracking(b.pathSealWrite)),
+			},
+		},
+		HelpSynopsis:    pathSealHelpSyn,
+		HelpDescription: pathSealHelpDesc,
+	}
+}
+
+func (b *backend) pathSealWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if resp, err := b.checkInputSize(ctx, req.Storage, data.Get("plaintext").(string)); resp != nil || err != nil {
+		return resp, err
+	}
+	format := data.Get("format").(string)
+	switch format {
+	case "base64":
+	case "ascii-armor":
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	markKeyUsed(ctx, data.Get("name").(string))
+	if err = entry.checkUsable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if resp, err := b.checkRateLimit(data.Get("name").(string), entry, 1); resp != nil || err != nil {
+		return resp, err
+	}
+	signer, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if !canSign(signer) {
+		return logical.ErrorResponse("no signing key available, the key does not have a signing capable key or subkey"), logical.ErrInvalidRequest
+	}
+	if err = checkExpiration(signer, time.Now(), true); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err = decryptPrivateKeys(signer, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if _, ok := signer.SigningKey(time.Now()); !ok {
+		return logical.ErrorResponse("the key does not have a valid signing key or subkey"), logical.ErrInvalidRequest
+	}
+
+	recipient := signer
+	if recipientKey := data.Get("recipient_key").(string); recipientKey != "" {
+		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(recipientKey))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		recipient = el[0]
+	}
+	if !canEncrypt(recipient) {
+		return logical.ErrorResponse("no encryption key available, the recipient key does not have an encryption capable key or subkey"), logical.ErrInvalidRequest
+	}
+	if _, ok := recipient.EncryptionKey(time.Now()); !ok {
+		return logical.ErrorResponse("the recipient key does not have a valid encryption key or subkey"), logical.ErrInvalidRequest
+	}
+	var config *packet.Config
+	if data.Get("aead").(bool) {
+		if err = checkAEADSupport(recipient); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config = &packet.Config{AEADConfig: &packet.AEADConfig{}}
+	}
+
+	var ciphertext bytes.Buffer
+	var ciphertextEncoder io.WriteCloser
+	if format == "base64" {
+		ciphertextEncoder = base64.NewEncoder(base64.StdEncoding, &ciphertext)
+	} else {
+		ciphertextEncoder, err = armor.Encode(&ciphertext, "PGP MESSAGE", nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	w, err := openpgp.Encrypt(ciphertextEncoder, []*openpgp.Entity{recipient}, signer, &openpgp.FileHints{IsBinary: true}, config)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := base64.NewDecoder(base64.StdEncoding, strings.NewReader(data.Get("plaintext").(string)))
+	if _, err = io.Copy(w, plaintext); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to decode plaintext as base64: %s", err)), logical.ErrInvalidRequest
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	if err = ciphertextEncoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"ciphertext":            ciphertext.String(),
+			"key_fingerprint":       hex.EncodeToString(signer.PrimaryKey.Fingerprint[:]),
+			"recipient_fingerprint": hex.EncodeToString(recipient.PrimaryKey.Fingerprint[:]),
+		},
+	}, nil
+}
+
+const pathSealHelpSyn = "Sign and encrypt a plaintext value in a single message using a named GPG key"
+
+const pathSealHelpDesc = `
+This path signs the plaintext with the named GPG key and encrypts it, in the
+same OpenPGP message, for a recipient public key or for the named key itself.
+The recipient decrypts the message and verifies the signature at once.
+`
@@ -0,0 +1,71 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"testing"
+)
+
+func TestGPG_Seal(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	publicKeys := map[string]interface{}{}
+	for _, name := range []string{"sender", "recipient"} {
+		handle(logical.UpdateOperation, "keys/"+name, map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		})
+		publicKeys[name] = handle(logical.ReadOperation, "keys/"+name, nil).Data["public_key"]
+	}
+
+	plaintext := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	if resp := handle(logical.UpdateOperation, "seal/notfound", map[string]interface{}{
+		"plaintext": plaintext,
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not exist")
+	}
+	if resp := handle(logical.UpdateOperation, "seal/sender", map[string]interface{}{
+		"plaintext":     plaintext,
+		"recipient_key": "Not a key",
+	}); !resp.IsError() {
+		t.Fatal("expected to fail, the recipient key is invalid")
+	}
+
+	tests := map[string]interface{}{
+		"recipient": publicKeys["recipient"],
+		"sender":    "",
+	}
+	for recipient, recipientKey := range tests {
+		resp := handle(logical.UpdateOperation, "seal/sender", map[string]interface{}{
+			"plaintext":     plaintext,
+			"recipient_key": recipientKey,
+		})
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+
+		resp = handle(logical.UpdateOperation, "decrypt/"+recipient, map[string]interface{}{
+			"ciphertext": resp.Data["ciphertext"],
+			"format":     "ascii-armor",
+			"signer_key": publicKeys["sender"],
+		})
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("the message sealed for %s has not been decrypted to the plaintext", recipient)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package gpg
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/hex"
@@ -9,17 +8,17 @@ import (
 	"io"
 	"strings"
 
-	"golang.org/x/crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
-	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/armor"
 )
 
 func pathShowSessionKey(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "show-session-key/" + framework.GenericNameRegex("name"),
+		Pattern: "show-session-key/" + keyNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
 				Type:        framework.TypeString,
@@ -38,6 +37,10 @@ func pathShowSessionKey(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "The ASCII-armored GPG key of the signer of the ciphertext. If present, the signature must be valid.",
 			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
@@ -63,14 +66,22 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 		return nil, err
 	}
 	if keyEntry == nil {
-		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+		return keyNotFound(data.Get("name").(string))
+	}
+	if err = keyEntry.checkUsable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if resp, err := b.checkRateLimit(data.Get("name").(string), keyEntry, 1); resp != nil || err != nil {
+		return resp, err
 	}
 
-	r := bytes.NewReader(keyEntry.SerializedKey)
-	keyring, err := openpgp.ReadKeyRing(r)
+	keyring, err := b.keyring(keyEntry)
 	if err != nil {
 		return nil, err
 	}
+	if err = decryptKeyringPrivateKeys(keyring, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
 
 	signerKey := data.Get("signer_key").(string)
 	if signerKey != "" {
@@ -115,7 +126,8 @@ func (b *backend) pathShowSessionKeyWrite(ctx context.Context, req *logical.Requ
 					sessionKey = fmt.Sprintf("%d:%s", encryptedKey.CipherFunc, strings.ToUpper(hex.EncodeToString(encryptedKey.Key)))
 					return &logical.Response{
 						Data: map[string]interface{}{
-							"session_key": sessionKey,
+							"session_key":     sessionKey,
+							"key_fingerprint": hex.EncodeToString(key.Entity.PrimaryKey.Fingerprint[:]),
 						},
 					}, nil
 				}
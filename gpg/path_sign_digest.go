@@ -0,0 +1,139 @@
+package gpg
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathSignDigest(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "sign-digest/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+			"digest": {
+				Type:        framework.TypeString,
+				Description: "The hex-encoded digest to sign",
+			},
+			"algorithm": {
+				Type:    framework.TypeString,
+				Default: "sha2-256",
+				Description: `Hash algorithm used to compute the digest. Valid values are:
+
+* sha2-224
+* sha2-256
+* sha2-384
+* sha2-512
+
+Defaults to "sha2-256".`,
+			},
+			"format": {
+				Type:        framework.TypeString,
+				Default:     "base64",
+				Description: `Encoding format to use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+			},
+			"allow_expired": {
+				Type:        framework.TypeBool,
+				Description: "Allows to use the key even if it or its signing subkeys expired.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: withMetrics("sign-digest", b.withUsageTracking(b.pathSignDigestWrite)),
+			},
+		},
+		HelpSynopsis:    pathSignDigestHelpSyn,
+		HelpDescription: pathSignDigestHelpDesc,
+	}
+}
+
+func (b *backend) pathSignDigestWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	algorithm := data.Get("algorithm").(string)
+	hash, ok := hashAlgorithm(algorithm)
+	if !ok {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), logical.ErrInvalidRequest
+	}
+	digest, err := hex.DecodeString(data.Get("digest").(string))
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to decode digest as hex: %s", err)), logical.ErrInvalidRequest
+	}
+	if len(digest) != hash.Size() {
+		return logical.ErrorResponse(fmt.Sprintf("invalid digest length %d; a %s digest is %d bytes long", len(digest), algorithm, hash.Size())), logical.ErrInvalidRequest
+	}
+
+	format := data.Get("format").(string)
+	switch format {
+	case "base64":
+	case "ascii-armor":
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	markKeyUsed(ctx, data.Get("name").(string))
+	if err = entry.checkUsable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if resp, err := b.checkRateLimit(data.Get("name").(string), entry, 1); resp != nil || err != nil {
+		return resp, err
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if !canSign(entity) {
+		return logical.ErrorResponse("no signing key available, the key does not have a signing capable key or subkey"), logical.ErrInvalidRequest
+	}
+	if err = decryptPrivateKeys(entity, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config := &packet.Config{DefaultHash: hash}
+	if data.Get("allow_expired").(bool) {
+		ignoreExpiration(entity)
+	} else if err = checkExpiration(entity, config.Now(), true); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if _, ok := entity.SigningKey(config.Now()); !ok {
+		return logical.ErrorResponse("the key does not have a valid signing key or subkey"), logical.ErrInvalidRequest
+	}
+
+	// The signed data is the digest itself, hashed again with the same algorithm. An OpenPGP
+	// signature over the original content cannot be made from its digest alone since the
+	// signature trailer is hashed after the content.
+	signature, err := sign(entity, digest, format, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"signature":       signature,
+			"key_fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
+		},
+	}, nil
+}
+
+const pathSignDigestHelpSyn = "Generate a signature for a precomputed digest using the named GPG key"
+
+const pathSignDigestHelpDesc = `
+This path signs a digest computed by the client, for example the SHA-256
+digest of a large artifact, without sending the artifact to Vault. The
+signature covers the digest bytes so it is verified against the digest,
+not against the original content.
+`
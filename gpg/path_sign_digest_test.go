@@ -0,0 +1,81 @@
+package gpg
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/hashicorp/vault/sdk/logical"
+	"testing"
+)
+
+func TestGPG_SignDigest(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha512.Sum384([]byte("the quick brown fox"))
+	signDigest := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign-digest/test",
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	if resp := signDigest(map[string]interface{}{"digest": hex.EncodeToString(digest[:])}); !resp.IsError() {
+		t.Fatal("expected to fail, the digest is not a sha2-256 digest")
+	}
+	if resp := signDigest(map[string]interface{}{"digest": "not hex", "algorithm": "sha2-384"}); !resp.IsError() {
+		t.Fatal("expected to fail, the digest is not hex-encoded")
+	}
+	if resp := signDigest(map[string]interface{}{"digest": hex.EncodeToString(digest[:]), "algorithm": "md5"}); !resp.IsError() {
+		t.Fatal("expected to fail, md5 is not supported")
+	}
+
+	for _, format := range []string{"base64", "ascii-armor"} {
+		resp := signDigest(map[string]interface{}{
+			"digest":    hex.EncodeToString(digest[:]),
+			"algorithm": "sha2-384",
+			"format":    format,
+		})
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+
+		reqVerify := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "verify/test",
+			Data: map[string]interface{}{
+				"input":     base64.StdEncoding.EncodeToString(digest[:]),
+				"signature": resp.Data["signature"],
+				"format":    format,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), reqVerify)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Data["valid"].(bool) {
+			t.Fatalf("expected the signature of the digest to be valid with format %s", format)
+		}
+		if resp.Data["hash_algorithm"] != "sha2-384" {
+			t.Fatalf("expected hash algorithm sha2-384, got %s", resp.Data["hash_algorithm"])
+		}
+	}
+}
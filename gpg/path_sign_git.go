@@ -0,0 +1,160 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathSignGit(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "sign-git/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+			"input": {
+				Type:        framework.TypeString,
+				Description: "The base64-encoded commit or tag object, as Git gives it to the signing program",
+			},
+			"algorithm": {
+				Type:    framework.TypeString,
+				Default: "sha2-256",
+				Description: `Hash algorithm to use. Valid values are:
+
+* sha2-224
+* sha2-256
+* sha2-384
+* sha2-512
+
+Defaults to "sha2-256".`,
+			},
+			"allow_expired": {
+				Type:        framework.TypeBool,
+				Description: "Allows to use the key even if it or its signing subkeys expired.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: withMetrics("sign-git", b.withUsageTracking(b.pathSignGitWrite)),
+			},
+		},
+		HelpSynopsis:    pathSignGitHelpSyn,
+		HelpDescription: pathSignGitHelpDesc,
+	}
+}
+
+func (b *backend) pathSignGitWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if resp, err := b.checkInputSize(ctx, req.Storage, data.Get("input").(string)); resp != nil || err != nil {
+		return resp, err
+	}
+	object, err := decodeInput(data.Get("input").(string), "base64")
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	objectType, err := gitObjectType(object)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	algorithm := data.Get("algorithm").(string)
+	hash, ok := hashAlgorithm(algorithm)
+	if !ok {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	markKeyUsed(ctx, data.Get("name").(string))
+	if err = entry.checkUsable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if resp, err := b.checkRateLimit(data.Get("name").(string), entry, 1); resp != nil || err != nil {
+		return resp, err
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if !canSign(entity) {
+		return logical.ErrorResponse("no signing key available, the key does not have a signing capable key or subkey"), logical.ErrInvalidRequest
+	}
+	if err = decryptPrivateKeys(entity, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config := &packet.Config{DefaultHash: hash}
+	if data.Get("allow_expired").(bool) {
+		ignoreExpiration(entity)
+	} else if err = checkExpiration(entity, config.Now(), true); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if _, ok := entity.SigningKey(config.Now()); !ok {
+		return logical.ErrorResponse("the key does not have a valid signing key or subkey"), logical.ErrInvalidRequest
+	}
+
+	// Git verifies the signature with "gpg --verify" over the object bytes as is, it is a detached
+	// signature of binary data like the one made by "gpg --detach-sign --armor".
+	signature, err := sign(entity, object, "ascii-armor", config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"signature":       signature,
+			"object_type":     objectType,
+			"key_fingerprint": hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]),
+		},
+	}, nil
+}
+
+// gitObjectType returns the type of the Git object to sign, only the commit and tag objects can be signed.
+// The object must not be signed already.
+func gitObjectType(object []byte) (string, error) {
+	var objectType string
+	switch {
+	case bytes.HasPrefix(object, []byte("tree ")):
+		objectType = "commit"
+	case bytes.HasPrefix(object, []byte("object ")):
+		objectType = "tag"
+	default:
+		return "", fmt.Errorf("input is not a Git commit or tag object")
+	}
+
+	// The commit signatures are in the gpgsig header while the tag signatures are appended to the message.
+	signed := bytes.Contains(object, []byte("-----BEGIN PGP SIGNATURE-----"))
+	if objectType == "commit" {
+		header := object
+		if end := bytes.Index(object, []byte("\n\n")); end >= 0 {
+			header = object[:end+1]
+		}
+		signed = bytes.Contains(header, []byte("\ngpgsig "))
+	}
+	if signed {
+		return "", fmt.Errorf("the Git %s object is already signed", objectType)
+	}
+	return objectType, nil
+}
+
+const pathSignGitHelpSyn = "Generate a signature for a Git commit or tag object using the named GPG key"
+
+const pathSignGitHelpDesc = `
+This path signs a Git commit or tag object the way Git expects it from its
+OpenPGP signing program, so the private key does not need to be present
+where the commit or tag is created. The returned ASCII-armored detached
+signature is added to the object by the caller.
+`
@@ -0,0 +1,116 @@
+package gpg
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"strings"
+	"testing"
+)
+
+const gitCommitObject = `tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904
+author Vault GPG test <vault@example.com> 1700000000 +0000
+committer Vault GPG test <vault@example.com> 1700000000 +0000
+
+Release v1.0.0
+`
+
+const gitTagObject = `object 2d3a8c46b4f0a4f2ab6b0bdbd3ae6a3c0c5d6f4e
+type commit
+tag v1.0.0
+tagger Vault GPG test <vault@example.com> 1700000000 +0000
+
+Release v1.0.0
+`
+
+func TestGPG_SignGit(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"email":     "vault@example.com",
+	})
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(handle(logical.ReadOperation, "keys/test", nil).Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signGit := func(object string, data map[string]interface{}) *logical.Response {
+		request := map[string]interface{}{"input": base64.StdEncoding.EncodeToString([]byte(object))}
+		for field, value := range data {
+			request[field] = value
+		}
+		return handle(logical.UpdateOperation, "sign-git/test", request)
+	}
+
+	invalid := map[string]struct {
+		object string
+		data   map[string]interface{}
+	}{
+		"the input is not a commit or a tag": {"blob content", nil},
+		"the commit is already signed":       {strings.Replace(gitCommitObject, "\n\n", "\ngpgsig -----BEGIN PGP SIGNATURE-----\n -----END PGP SIGNATURE-----\n\n", 1), nil},
+		"the tag is already signed":          {gitTagObject + "-----BEGIN PGP SIGNATURE-----\n-----END PGP SIGNATURE-----\n", nil},
+		"md5 is not supported":               {gitCommitObject, map[string]interface{}{"algorithm": "md5"}},
+	}
+	for reason, request := range invalid {
+		if resp := signGit(request.object, request.data); !resp.IsError() {
+			t.Fatalf("expected to fail, %s", reason)
+		}
+	}
+	if resp := handle(logical.UpdateOperation, "sign-git/notfound", map[string]interface{}{"input": base64.StdEncoding.EncodeToString([]byte(gitCommitObject))}); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not exist")
+	}
+
+	objects := map[string]string{
+		"commit": gitCommitObject,
+		"tag":    gitTagObject,
+	}
+	for objectType, object := range objects {
+		resp := signGit(object, nil)
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		if resp.Data["object_type"] != objectType {
+			t.Fatalf("expected a %s object, got %#v", objectType, resp.Data["object_type"])
+		}
+		signature := resp.Data["signature"].(string)
+		if !strings.HasPrefix(signature, "-----BEGIN PGP SIGNATURE-----") {
+			t.Fatalf("expected an armored signature, got %s", signature)
+		}
+		if _, err = openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(object), strings.NewReader(signature), nil); err != nil {
+			t.Fatalf("expected the signature of the %s object to be valid: %s", objectType, err)
+		}
+
+		block, err := armor.Decode(strings.NewReader(signature))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := packet.Read(block.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig, ok := p.(*packet.Signature); !ok || sig.SigType != packet.SigTypeBinary {
+			t.Fatalf("expected a signature of binary data, got %#v", p)
+		}
+
+		// Any change of the object, even of its line endings, invalidates the signature.
+		altered := strings.ReplaceAll(object, "\n", "\r\n")
+		if _, err = openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(altered), strings.NewReader(signature), nil); err == nil {
+			t.Fatalf("expected the signature of the altered %s object to be invalid", objectType)
+		}
+	}
+}
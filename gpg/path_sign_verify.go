@@ -5,17 +5,25 @@ import (
 	"context"
 	"crypto"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
-	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/packet"
+	"io"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 func pathSign(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "sign/" + framework.GenericNameRegex("name") + framework.OptionalParamRegex("urlalgorithm"),
+		Pattern: "sign/" + keyNameRegex("name") + "(/(?P<urlalgorithm>sha2-(224|256|384|512)))?",
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
 				Type:        framework.TypeString,
@@ -25,6 +33,11 @@ func pathSign(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "The base64-encoded input data",
 			},
+			"input_type": {
+				Type:        framework.TypeString,
+				Default:     "base64",
+				Description: `The encoding of the input data. Can be "base64" or "raw" to pass UTF-8 text as is. Defaults to "base64".`,
+			},
 			"urlalgorithm": {
 				Type:        framework.TypeString,
 				Description: "Hash algorithm to use (POST URL parameter)",
@@ -44,12 +57,41 @@ Defaults to "sha2-256".`,
 			"format": {
 				Type:        framework.TypeString,
 				Default:     "base64",
-				Description: `Encoding format to use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
+				Description: `Encoding format to use. Can be "base64", "ascii-armor", "clearsign" or "compact" for URL-safe base64 without padding. Defaults to "base64".`,
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+			"subkey_fingerprint": {
+				Type:        framework.TypeString,
+				Description: "The fingerprint of the signing subkey to use. If empty, the subkey is selected automatically.",
+			},
+			"allow_expired": {
+				Type:        framework.TypeBool,
+				Description: "Allows to use the key even if it or its signing subkeys expired.",
+			},
+			"signing_time": {
+				Type:        framework.TypeString,
+				Description: "The RFC3339 creation time of the signature. If empty, the current time is used.",
+			},
+			"batch_input": {
+				Type:        framework.TypeStringSlice,
+				Description: "A list of input data to sign, encoded as specified by input_type. When set, input is ignored and a signature or an error is returned for each item, in the same order.",
+			},
+			"notations": {
+				Type:        framework.TypeKVPairs,
+				Description: `Human-readable notation data added to the signature, mapping notation names of the form "name@domain" to their values.`,
+			},
+			"critical_notations": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The names of the notations marked as critical, verifiers not knowing them reject the signature.",
 			},
+			"context": operationContextField(),
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
-				Callback: b.pathSignWrite,
+				Callback: withMetrics("sign", b.withUsageTracking(withOperationContext(b.pathSignWrite))),
 			},
 		},
 		HelpSynopsis:    pathSignHelpSyn,
@@ -58,30 +100,17 @@ Defaults to "sha2-256".`,
 }
 
 func pathVerify(b *backend) *framework.Path {
+	fields := verifyFields()
+	fields["name"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "The key to use",
+	}
 	return &framework.Path{
-		Pattern: "verify/" + framework.GenericNameRegex("name"),
-		Fields: map[string]*framework.FieldSchema{
-			"name": {
-				Type:        framework.TypeString,
-				Description: "The key to use",
-			},
-			"input": {
-				Type:        framework.TypeString,
-				Description: "The base64-encoded input data to verify",
-			},
-			"signature": {
-				Type:        framework.TypeString,
-				Description: "The signature",
-			},
-			"format": {
-				Type:        framework.TypeString,
-				Default:     "base64",
-				Description: `Encoding format the signature use. Can be "base64" or "ascii-armor". Defaults to "base64".`,
-			},
-		},
+		Pattern: "verify/" + keyNameRegex("name"),
+		Fields:  fields,
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
-				Callback: b.pathVerifyWrite,
+				Callback: withMetrics("verify", b.pathVerifyWrite),
 			},
 		},
 		HelpSynopsis:    pathVerifyHelpSyn,
@@ -89,11 +118,77 @@ func pathVerify(b *backend) *framework.Path {
 	}
 }
 
+func pathVerifyKeys(b *backend) *framework.Path {
+	fields := verifyFields()
+	fields["names"] = &framework.FieldSchema{
+		Type:        framework.TypeCommaStringSlice,
+		Description: "The names of the candidate keys",
+	}
+	fields["tag"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Selects the keys having the tag as candidates, given as "key:value" or as "key" to match any value`,
+	}
+	return &framework.Path{
+		Pattern: "verify$",
+		Fields:  fields,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: withMetrics("verify-multi", b.pathVerifyKeysWrite),
+			},
+		},
+		HelpSynopsis:    pathVerifyKeysHelpSyn,
+		HelpDescription: pathVerifyKeysHelpDesc,
+	}
+}
+
+// verifyFields returns the fields describing the signed message sent to the verify endpoints.
+func verifyFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"input": {
+			Type:        framework.TypeString,
+			Description: "The base64-encoded input data to verify. Not used with the clearsign format.",
+		},
+		"input_type": {
+			Type:        framework.TypeString,
+			Default:     "base64",
+			Description: `The encoding of the input data. Can be "base64" or "raw" to pass UTF-8 text as is. Defaults to "base64".`,
+		},
+		"signature": {
+			Type:        framework.TypeString,
+			Description: "The signature, or the whole clearsigned message with the clearsign format",
+		},
+		"format": {
+			Type:        framework.TypeString,
+			Default:     "base64",
+			Description: `Encoding format the signature use. Can be "base64", "ascii-armor", "clearsign" or "compact" for URL-safe base64 without padding. Defaults to "base64".`,
+		},
+		"known_notations": {
+			Type:        framework.TypeCommaStringSlice,
+			Description: "The names of the critical notations understood by the caller. A signature with another critical notation is not valid.",
+		},
+		"allow_expired": {
+			Type:        framework.TypeBool,
+			Description: "Accepts the signatures made by a key or subkey that has expired. Such signatures are not valid otherwise.",
+		},
+	}
+}
+
 func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	inputB64 := data.Get("input").(string)
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	if resp, err := b.checkInputSize(ctx, req.Storage, append(data.Get("batch_input").([]string), data.Get("input").(string))...); resp != nil || err != nil {
+		return resp, err
+	}
+	inputType := data.Get("input_type").(string)
+	if err := checkInputType(inputType); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	batchInput := data.Get("batch_input").([]string)
+	var input []byte
+	if len(batchInput) == 0 {
+		var err error
+		input, err = decodeInput(data.Get("input").(string), inputType)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 	}
 
 	config := packet.Config{}
@@ -102,25 +197,42 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, data
 	if algorithm == "" {
 		algorithm = data.Get("algorithm").(string)
 	}
-	switch algorithm {
-	case "sha2-224":
-		config.DefaultHash = crypto.SHA224
-	case "sha2-256":
-		config.DefaultHash = crypto.SHA256
-	case "sha2-384":
-		config.DefaultHash = crypto.SHA384
-	case "sha2-512":
-		config.DefaultHash = crypto.SHA512
-	default:
+	hash, ok := hashAlgorithm(algorithm)
+	if !ok {
 		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
+	config.DefaultHash = hash
+
+	if signingTime := data.Get("signing_time").(string); signingTime != "" {
+		t, err := time.Parse(time.RFC3339, signingTime)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid signing_time: %s", err)), logical.ErrInvalidRequest
+		}
+		config.Time = func() time.Time {
+			return t
+		}
+		// The salt notation would make the signatures differ for the same signing time.
+		randomize := false
+		config.NonDeterministicSignaturesViaNotation = &randomize
+	}
+
+	notations, err := signatureNotations(data.Get("notations").(map[string]string), data.Get("critical_notations").([]string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config.SignatureNotations = notations
 
 	format := data.Get("format").(string)
 	switch format {
 	case "base64":
 	case "ascii-armor":
+	case "compact":
+	case "clearsign":
+		if len(batchInput) == 0 && !utf8.Valid(input) {
+			return logical.ErrorResponse("input must be UTF-8 encoded text to be clearsigned"), logical.ErrInvalidRequest
+		}
 	default:
-		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
+		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\", \"ascii-armor\", \"clearsign\" or \"compact\"", format)), nil
 	}
 
 	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
@@ -128,53 +240,185 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, data
 		return nil, err
 	}
 	if entry == nil {
-		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+		return keyNotFound(data.Get("name").(string))
+	}
+	markKeyUsed(ctx, data.Get("name").(string))
+	if err = entry.checkUsable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if resp, err := b.checkRateLimit(data.Get("name").(string), entry, len(batchInput)); resp != nil || err != nil {
+		return resp, err
 	}
 	entity, err := b.entity(entry)
 	if err != nil {
 		return nil, err
 	}
-
-	message := bytes.NewReader(input)
-	var signature bytes.Buffer
-	switch format {
-	case "ascii-armor":
-		err = openpgp.ArmoredDetachSign(&signature, entity, message, &config)
-		if err != nil {
-			return nil, err
+	if !canSign(entity) {
+		return logical.ErrorResponse("no signing key available, the key does not have a signing capable key or subkey"), logical.ErrInvalidRequest
+	}
+	if err = decryptPrivateKeys(entity, data.Get("passphrase").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if data.Get("allow_expired").(bool) {
+		ignoreExpiration(entity)
+	} else if err = checkExpiration(entity, config.Now(), true); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if subkeyFingerprint := data.Get("subkey_fingerprint").(string); subkeyFingerprint != "" {
+		subkey, ok := findSubkey(entity, subkeyFingerprint)
+		if !ok {
+			return logical.ErrorResponse(fmt.Sprintf("subkey %s not found", subkeyFingerprint)), logical.ErrInvalidRequest
 		}
-	case "base64":
-		encoder := base64.NewEncoder(base64.StdEncoding, &signature)
-		err = openpgp.DetachSign(encoder, entity, message, &config)
-		if err != nil {
-			return nil, err
+		if _, ok := entity.SigningKeyById(config.Now(), subkey.PublicKey.KeyId); !ok {
+			return logical.ErrorResponse(fmt.Sprintf("subkey %s is not a valid signing subkey", subkeyFingerprint)), logical.ErrInvalidRequest
 		}
-		err = encoder.Close()
-		if err != nil {
-			return nil, err
+		config.SigningKeyId = subkey.PublicKey.KeyId
+	}
+	if _, ok := entity.SigningKeyById(config.Now(), config.SigningKey()); !ok {
+		if config.Time != nil {
+			return logical.ErrorResponse("the key does not have a valid signing key or subkey at the signing time"), logical.ErrInvalidRequest
 		}
+		return logical.ErrorResponse("the key does not have a valid signing key or subkey"), logical.ErrInvalidRequest
 	}
+	fingerprint := hex.EncodeToString(entity.PrimaryKey.Fingerprint[:])
 
+	if len(batchInput) > 0 {
+		batchResults := make([]map[string]interface{}, 0, len(batchInput))
+		for _, item := range batchInput {
+			input, err := decodeInput(item, inputType)
+			if err != nil {
+				batchResults = append(batchResults, map[string]interface{}{
+					"error": err.Error(),
+				})
+				continue
+			}
+			if format == "clearsign" && !utf8.Valid(input) {
+				batchResults = append(batchResults, map[string]interface{}{
+					"error": "input must be UTF-8 encoded text to be clearsigned",
+				})
+				continue
+			}
+			signature, err := sign(entity, input, format, &config)
+			if err != nil {
+				return nil, err
+			}
+			batchResults = append(batchResults, map[string]interface{}{
+				"signature": signature,
+			})
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"batch_results":   batchResults,
+				"key_fingerprint": fingerprint,
+			},
+		}, nil
+	}
+
+	signature, err := sign(entity, input, format, &config)
+	if err != nil {
+		return nil, err
+	}
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"signature": signature.String(),
+			"signature":       signature,
+			"key_fingerprint": fingerprint,
 		},
 	}, nil
 }
 
-func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	inputB64 := data.Get("input").(string)
-	input, err := base64.StdEncoding.DecodeString(inputB64)
+// checkInputType returns an error if the input type is not supported.
+func checkInputType(inputType string) error {
+	switch inputType {
+	case "base64", "raw":
+		return nil
+	default:
+		return fmt.Errorf("unsupported input type %s; must be \"base64\" or \"raw\"", inputType)
+	}
+}
+
+// decodeInput returns the bytes of the input data according to its type.
+func decodeInput(input string, inputType string) ([]byte, error) {
+	if inputType == "raw" {
+		return []byte(input), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(input)
 	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+		return nil, fmt.Errorf("unable to decode input as base64: %s", err)
 	}
+	return decoded, nil
+}
 
-	format := data.Get("format").(string)
+// signatureNotations returns the human-readable notations to add to a signature, sorted by name.
+func signatureNotations(notations map[string]string, critical []string) ([]*packet.Notation, error) {
+	names := make([]string, 0, len(notations))
+	for name := range notations {
+		// The names without a domain are reserved to the IETF, see RFC 4880 section 5.2.3.16
+		if at := strings.Index(name, "@"); at <= 0 || at == len(name)-1 {
+			return nil, fmt.Errorf("invalid notation name %q; must be of the form \"name@domain\"", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	isCritical := make(map[string]bool, len(critical))
+	for _, name := range critical {
+		if _, ok := notations[name]; !ok {
+			return nil, fmt.Errorf("critical notation %s is not in the notations", name)
+		}
+		isCritical[name] = true
+	}
+	result := make([]*packet.Notation, 0, len(names))
+	for _, name := range names {
+		result = append(result, &packet.Notation{
+			Name:            name,
+			Value:           []byte(notations[name]),
+			IsCritical:      isCritical[name],
+			IsHumanReadable: true,
+		})
+	}
+	return result, nil
+}
+
+// sign returns the signature of the input made with the entity and encoded in the given format.
+func sign(entity *openpgp.Entity, input []byte, format string, config *packet.Config) (string, error) {
+	message := bytes.NewReader(input)
+	var signature bytes.Buffer
 	switch format {
-	case "base64":
 	case "ascii-armor":
-	default:
-		return logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\" or \"ascii-armor\"", format)), nil
+		if err := openpgp.ArmoredDetachSign(&signature, entity, message, config); err != nil {
+			return "", err
+		}
+	case "base64", "compact":
+		encoding := base64.StdEncoding
+		if format == "compact" {
+			encoding = base64.RawURLEncoding
+		}
+		encoder := base64.NewEncoder(encoding, &signature)
+		if err := openpgp.DetachSign(encoder, entity, message, config); err != nil {
+			return "", err
+		}
+		if err := encoder.Close(); err != nil {
+			return "", err
+		}
+	case "clearsign":
+		signingKey, _ := entity.SigningKeyById(config.Now(), config.SigningKey())
+		w, err := clearsign.Encode(&signature, signingKey.PrivateKey, config)
+		if err != nil {
+			return "", err
+		}
+		if _, err = w.Write(input); err != nil {
+			return "", err
+		}
+		if err = w.Close(); err != nil {
+			return "", err
+		}
+	}
+	return signature.String(), nil
+}
+
+func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	signed, resp, err := b.readSignedMessage(ctx, req, data)
+	if resp != nil || err != nil {
+		return resp, err
 	}
 
 	keyEntry, err := b.key(ctx, req.Storage, data.Get("name").(string))
@@ -182,35 +426,248 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, dat
 		return nil, err
 	}
 	if keyEntry == nil {
-		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+		return keyNotFound(data.Get("name").(string))
 	}
 
-	r := bytes.NewReader(keyEntry.SerializedKey)
-	keyring, err := openpgp.ReadKeyRing(r)
+	keyring, err := b.keyring(keyEntry)
 	if err != nil {
 		return nil, err
 	}
 
-	signature := strings.NewReader(data.Get("signature").(string))
-	message := bytes.NewReader(input)
+	resp, _, err = signed.verify(keyring)
+	return resp, err
+}
+
+func (b *backend) pathVerifyKeysWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names := data.Get("names").([]string)
+	tag := data.Get("tag").(string)
+	if (len(names) == 0) == (tag == "") {
+		return logical.ErrorResponse("exactly one of names or tag must be set to select the candidate keys"), logical.ErrInvalidRequest
+	}
+	signed, resp, err := b.readSignedMessage(ctx, req, data)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	filter := tag != ""
+	if filter {
+		names, err = b.keyNames(ctx, req.Storage, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	// All the versions of the candidate keys are verified against at once, the signer is then found back
+	// from the fingerprint of its primary key.
+	var keyring openpgp.EntityList
+	keyNames := make(map[string]string)
+	for _, name := range names {
+		entry, err := b.key(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			if filter {
+				continue
+			}
+			return keyNotFound(name)
+		}
+		if filter && !entry.hasTag(tag) {
+			continue
+		}
+		versions, err := b.keyring(entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range versions {
+			keyNames[hex.EncodeToString(entity.PrimaryKey.Fingerprint)] = name
+		}
+		keyring = append(keyring, versions...)
+	}
+
+	resp, signer, err := signed.verify(keyring)
+	if signer != nil {
+		resp.Data["key_name"] = keyNames[hex.EncodeToString(signer.PrimaryKey.Fingerprint)]
+	}
+	return resp, err
+}
+
+// signedMessage is a message and its detached signature sent to a verify endpoint.
+type signedMessage struct {
+	input     []byte
+	signature []byte
+	// plaintext is the text of a clearsigned message, returned by the verification
+	plaintext      []byte
+	clearsign      bool
+	knownNotations []string
+	allowExpired   bool
+}
+
+// readSignedMessage decodes the input and the signature sent to a verify endpoint.
+func (b *backend) readSignedMessage(ctx context.Context, req *logical.Request, data *framework.FieldData) (*signedMessage, *logical.Response, error) {
+	if resp, err := b.checkInputSize(ctx, req.Storage, data.Get("input").(string), data.Get("signature").(string)); resp != nil || err != nil {
+		return nil, resp, err
+	}
+	inputType := data.Get("input_type").(string)
+	if err := checkInputType(inputType); err != nil {
+		return nil, logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	format := data.Get("format").(string)
 	switch format {
 	case "base64":
-		decoder := base64.NewDecoder(base64.StdEncoding, signature)
-		_, err = openpgp.CheckDetachedSignature(keyring, message, decoder)
 	case "ascii-armor":
-		_, err = openpgp.CheckArmoredDetachedSignature(keyring, message, signature)
+	case "compact":
+	case "clearsign":
+		if data.Get("input").(string) != "" {
+			return nil, logical.ErrorResponse("input must not be set with the clearsign format, the signed text is read from the clearsigned message"), logical.ErrInvalidRequest
+		}
+	default:
+		return nil, logical.ErrorResponse(fmt.Sprintf("unsupported encoding format %s; must be \"base64\", \"ascii-armor\", \"clearsign\" or \"compact\"", format)), nil
+	}
+
+	signed := &signedMessage{
+		clearsign:      format == "clearsign",
+		knownNotations: data.Get("known_notations").([]string),
+		allowExpired:   data.Get("allow_expired").(bool),
+	}
+	var err error
+	if signed.clearsign {
+		block, _ := clearsign.Decode([]byte(data.Get("signature").(string)))
+		if block == nil {
+			return nil, logical.ErrorResponse("unable to parse signature: no clearsigned message found"), logical.ErrInvalidRequest
+		}
+		signed.input, signed.plaintext = block.Bytes, block.Plaintext
+		signed.signature, err = io.ReadAll(block.ArmoredSignature.Body)
+	} else {
+		signed.input, err = decodeInput(data.Get("input").(string), inputType)
+		if err != nil {
+			return nil, logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		signed.signature, err = decodeSignature(data.Get("signature").(string), format)
+	}
+	if err != nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("unable to parse signature: %s", err)), logical.ErrInvalidRequest
+	}
+	return signed, nil, nil
+}
+
+// verify checks the signature of the message against the keyring, the entity that made a valid signature is returned.
+func (signed *signedMessage) verify(keyring openpgp.EntityList) (*logical.Response, *openpgp.Entity, error) {
+	config := &packet.Config{KnownNotations: make(map[string]bool, len(signed.knownNotations))}
+	for _, name := range signed.knownNotations {
+		config.KnownNotations[name] = true
+	}
+	sig, _, err := openpgp.VerifyDetachedSignature(keyring, bytes.NewReader(signed.input), bytes.NewReader(signed.signature), config)
+
+	var valid bool
+	switch err {
+	case nil:
+		valid = true
+	case errors.ErrKeyExpired:
+		// The signatures of the expired keys are only accepted when the caller asks for it
+		valid = signed.allowExpired
+	case errors.ErrUnknownIssuer, errors.ErrKeyRevoked, errors.ErrSignatureExpired:
+		valid = false
+	default:
+		if _, ok := err.(errors.SignatureError); !ok {
+			return logical.ErrorResponse(fmt.Sprintf("unable to parse signature: %s", err)), nil, logical.ErrInvalidRequest
+		}
+		valid = false
 	}
 
 	resp := &logical.Response{
 		Data: map[string]interface{}{
-			"valid": err == nil,
+			"valid": valid,
 		},
 	}
+	// The issuer is read from the signature itself so it is known even if it is not in the keyring.
+	if p, err := packet.NewReader(bytes.NewReader(signed.signature)).Next(); err == nil {
+		if issuer, ok := p.(*packet.Signature); ok && issuer.IssuerKeyId != nil {
+			resp.Data["issuer_key_id"] = fmt.Sprintf("%016x", *issuer.IssuerKeyId)
+		}
+	}
+	var signer *openpgp.Entity
+	if valid && sig != nil {
+		if keys := keyring.KeysById(*sig.IssuerKeyId); len(keys) > 0 {
+			resp.Data["signer_fingerprint"] = hex.EncodeToString(keys[0].PublicKey.Fingerprint[:])
+			signer = keys[0].Entity
+		}
+		resp.Data["creation_time"] = sig.CreationTime.UTC().Format(time.RFC3339)
+		resp.Data["hash_algorithm"] = hashAlgorithmName(sig.Hash)
+		notations := make(map[string]string)
+		for _, notation := range sig.Notations {
+			if notation.IsHumanReadable {
+				notations[notation.Name] = string(notation.Value)
+			}
+		}
+		resp.Data["notations"] = notations
+	}
+	if signed.clearsign {
+		resp.Data["plaintext"] = base64.StdEncoding.EncodeToString(signed.plaintext)
+	}
+
+	return resp, signer, nil
+}
+
+// decodeSignature returns the binary signature packets encoded in the given format.
+func decodeSignature(signature string, format string) ([]byte, error) {
+	switch format {
+	case "base64":
+		return base64.StdEncoding.DecodeString(signature)
+	case "compact":
+		return base64.RawURLEncoding.DecodeString(signature)
+	}
+	block, err := armor.Decode(strings.NewReader(signature))
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != openpgp.SignatureType {
+		return nil, fmt.Errorf("expected armor block of type %s, got %s", openpgp.SignatureType, block.Type)
+	}
+	return io.ReadAll(block.Body)
+}
 
-	return resp, nil
+// hashAlgorithm returns the hash algorithm with the given name.
+func hashAlgorithm(name string) (crypto.Hash, bool) {
+	switch name {
+	case "sha2-224":
+		return crypto.SHA224, true
+	case "sha2-256":
+		return crypto.SHA256, true
+	case "sha2-384":
+		return crypto.SHA384, true
+	case "sha2-512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// hashAlgorithmName returns the name of the hash algorithm as accepted by the sign endpoint.
+func hashAlgorithmName(hash crypto.Hash) string {
+	switch hash {
+	case crypto.SHA224:
+		return "sha2-224"
+	case crypto.SHA256:
+		return "sha2-256"
+	case crypto.SHA384:
+		return "sha2-384"
+	case crypto.SHA512:
+		return "sha2-512"
+	default:
+		return strings.ToLower(hash.String())
+	}
 }
 
 const pathSignHelpSyn = "Generate a signature for input data using the named GPG key"
 const pathSignHelpDesc = "Generates a signature of the input data using the named GPG key."
+const pathVerifyKeysHelpSyn = "Verify a signature against several candidate GPG keys"
+
+const pathVerifyKeysHelpDesc = `
+This path verifies a signature against the keys given by name, or having a
+tag, and all their previous versions. The name of the key that made a valid
+signature is returned.
+`
+
 const pathVerifyHelpSyn = "Verify a signature for input data created using the named GPG key"
 const pathVerifyHelpDesc = "Verifies a signature of the input data using the named GPG key."
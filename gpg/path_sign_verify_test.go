@@ -1,9 +1,19 @@
 package gpg
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGPG_SignVerify(t *testing.T) {
@@ -130,11 +140,21 @@ func TestGPG_SignVerify(t *testing.T) {
 	signature = signRequest(req, "test", false, "")
 	verifyRequest(req, "test", false, true, signature)
 
+	req.Data["format"] = "compact"
+	signature = signRequest(req, "test", false, "")
+	if strings.ContainsAny(signature, "+/=") {
+		t.Fatalf("expected a URL-safe signature without padding, got %s", signature)
+	}
+	verifyRequest(req, "test", false, true, signature)
+
 	// Test validation format mismatch
 	req.Data["format"] = "ascii-armor"
 	signature = signRequest(req, "test", false, "")
 	req.Data["format"] = "base64"
-	verifyRequest(req, "test", false, false, signature)
+	verifyRequest(req, "test", true, false, signature)
+
+	// Test malformed signature
+	verifyRequest(req, "test", true, false, "bm90IGEgc2lnbmF0dXJl")
 
 	// Test bad format
 	req.Data["format"] = "notexisting"
@@ -151,3 +171,768 @@ func TestGPG_SignVerify(t *testing.T) {
 	signRequest(req, "test", true, "")
 	verifyRequest(req, "test", true, false, signature)
 }
+
+func TestGPG_SignClearsign(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqRead := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := resp.Data["fingerprint"]
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqSign := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Data: map[string]interface{}{
+			"input":  base64.StdEncoding.EncodeToString([]byte("the quick brown fox\r\njumps over the lazy dog\n")),
+			"format": "clearsign",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp.Data["key_fingerprint"] != fingerprint {
+		t.Fatalf("expected fingerprint %s, got %s", fingerprint, resp.Data["key_fingerprint"])
+	}
+	if _, ok := resp.Data["fingerprint"]; ok {
+		t.Fatal("expected the fingerprint to only be returned as key_fingerprint")
+	}
+
+	block, _ := clearsign.Decode([]byte(resp.Data["signature"].(string)))
+	if block == nil {
+		t.Fatalf("no clearsigned message found: %s", resp.Data["signature"])
+	}
+	if string(block.Plaintext) != "the quick brown fox\njumps over the lazy dog\n" {
+		t.Fatalf("unexpected clearsigned text: %q", block.Plaintext)
+	}
+	if _, err = block.VerifySignature(keyring, nil); err != nil {
+		t.Fatalf("clearsigned message signature is not valid: %s", err)
+	}
+
+	reqVerify := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "verify/test",
+		Data: map[string]interface{}{
+			"signature": resp.Data["signature"],
+			"format":    "clearsign",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqVerify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data["valid"].(bool) {
+		t.Fatal("expected the clearsigned message to be valid")
+	}
+	if resp.Data["plaintext"] != base64.StdEncoding.EncodeToString(block.Plaintext) {
+		t.Fatalf("unexpected recovered text %s", resp.Data["plaintext"])
+	}
+	tampered := strings.Replace(reqVerify.Data["signature"].(string), "lazy dog", "lazy cat", 1)
+	reqVerify.Data["signature"] = tampered
+	resp, err = b.HandleRequest(context.Background(), reqVerify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["valid"].(bool) {
+		t.Fatal("expected the tampered clearsigned message to not be valid")
+	}
+	reqVerify.Data["input"] = base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	if resp, _ = b.HandleRequest(context.Background(), reqVerify); !resp.IsError() {
+		t.Fatal("expected to fail, input cannot be used with the clearsign format")
+	}
+	reqVerify.Data = map[string]interface{}{"signature": "not clearsigned", "format": "clearsign"}
+	if resp, _ = b.HandleRequest(context.Background(), reqVerify); !resp.IsError() {
+		t.Fatal("expected to fail, the signature is not a clearsigned message")
+	}
+
+	reqSign.Data["input"] = base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe})
+	resp, _ = b.HandleRequest(context.Background(), reqSign)
+	if !resp.IsError() {
+		t.Fatal("expected to fail, input is not UTF-8 encoded")
+	}
+}
+
+func TestGPG_SignWithSubkey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fingerprints []string
+	for i := 0; i < 2; i++ {
+		reqSubkey := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "subkey/test",
+			Data: map[string]interface{}{
+				"usage": "sign",
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), reqSubkey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprints = append(fingerprints, resp.Data["fingerprint"].(string))
+	}
+
+	reqRead := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), reqRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(resp.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptionSubkey := resp.Data["subkeys"].([]map[string]interface{})[0]["fingerprint"].(string)
+
+	for _, format := range []string{"base64", "ascii-armor", "clearsign"} {
+		reqSign := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/test",
+			Data: map[string]interface{}{
+				"input":              "dGhlIHF1aWNrIGJyb3duIGZveA==",
+				"format":             format,
+				"subkey_fingerprint": strings.ToUpper(fingerprints[0]),
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), reqSign)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+
+		signature := resp.Data["signature"].(string)
+		var signer *openpgp.Entity
+		switch format {
+		case "base64":
+			decoded, _ := base64.StdEncoding.DecodeString(signature)
+			signer, err = openpgp.CheckDetachedSignature(keyring, strings.NewReader("the quick brown fox"), bytes.NewReader(decoded), nil)
+		case "ascii-armor":
+			signer, err = openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader("the quick brown fox"), strings.NewReader(signature), nil)
+		case "clearsign":
+			block, _ := clearsign.Decode([]byte(signature))
+			signer, err = block.VerifySignature(keyring, nil)
+		}
+		if err != nil {
+			t.Fatalf("signature is not valid: %s", err)
+		}
+		if signer == nil {
+			t.Fatal("no signer found")
+		}
+		subkey, _ := findSubkey(keyring[0], fingerprints[0])
+		if signatureIssuer(t, format, signature) != subkey.PublicKey.KeyId {
+			t.Fatalf("%s signature has not been made with subkey %s", format, fingerprints[0])
+		}
+	}
+
+	signMustFail := func(subkeyFingerprint string) {
+		reqSign := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/test",
+			Data: map[string]interface{}{
+				"input":              "dGhlIHF1aWNrIGJyb3duIGZveA==",
+				"subkey_fingerprint": subkeyFingerprint,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), reqSign)
+		if !resp.IsError() {
+			t.Fatalf("expected to fail, subkey fingerprint: %s", subkeyFingerprint)
+		}
+	}
+
+	signMustFail("0000000000000000000000000000000000000000")
+	signMustFail(encryptionSubkey)
+}
+
+// signatureIssuer returns the key ID of the issuer of a signature produced by the sign endpoint.
+func signatureIssuer(t *testing.T, format, signature string) uint64 {
+	var r io.Reader
+	switch format {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, strings.NewReader(signature))
+	case "ascii-armor":
+		block, err := armor.Decode(strings.NewReader(signature))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = block.Body
+	case "clearsign":
+		block, _ := clearsign.Decode([]byte(signature))
+		r = block.ArmoredSignature.Body
+	}
+	p, err := packet.Read(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok || sig.IssuerKeyId == nil {
+		t.Fatalf("expected a signature packet with an issuer, got %T", p)
+	}
+	return *sig.IssuerKeyId
+}
+
+func TestGPG_SignBatch(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []interface{}{
+		"dGhlIHF1aWNrIGJyb3duIGZveA==",
+		"Not base64",
+		"anVtcHMgb3ZlciB0aGUgbGF6eSBkb2c=",
+	}
+	reqSign := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Data: map[string]interface{}{
+			"batch_input": inputs,
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), reqSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	batchResults := resp.Data["batch_results"].([]map[string]interface{})
+	if len(batchResults) != len(inputs) {
+		t.Fatalf("expected %d results, got %d", len(inputs), len(batchResults))
+	}
+	if _, ok := batchResults[1]["error"]; !ok {
+		t.Fatalf("expected an error for the invalid input, got %#v", batchResults[1])
+	}
+
+	for _, i := range []int{0, 2} {
+		reqVerify := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "verify/test",
+			Data: map[string]interface{}{
+				"input":     inputs[i],
+				"signature": batchResults[i]["signature"],
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), reqVerify)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Data["valid"].(bool) {
+			t.Fatalf("signature of input %d is not valid", i)
+		}
+	}
+}
+
+func TestGPG_SignVerifyRawInput(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqSign := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Data: map[string]interface{}{
+			"input":      "the quick brown fox",
+			"input_type": "hex",
+		},
+	}
+	resp, _ := b.HandleRequest(context.Background(), reqSign)
+	if !resp.IsError() {
+		t.Fatal("expected to fail, hex is not a supported input type")
+	}
+	reqSign.Data["input_type"] = "raw"
+	resp, err = b.HandleRequest(context.Background(), reqSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := resp.Data["signature"].(string)
+
+	verify := func(input string, inputType string) bool {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "verify/test",
+			Data: map[string]interface{}{
+				"input":      input,
+				"input_type": inputType,
+				"signature":  signature,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data["valid"].(bool)
+	}
+	if !verify("the quick brown fox", "raw") {
+		t.Fatal("expected the signature of the raw input to be valid")
+	}
+	if !verify("dGhlIHF1aWNrIGJyb3duIGZveA==", "base64") {
+		t.Fatal("expected the signature to be valid for the same base64-encoded input")
+	}
+	if verify("the quick brown fox jumps", "raw") {
+		t.Fatal("expected the signature of a different input to be invalid")
+	}
+}
+
+func TestGPG_VerifyReturnsSignerInfo(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	for _, name := range []string{"test", "other"} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"real_name": "Vault GPG test",
+				"algorithm": "eddsa",
+			},
+		}
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reqSubkey := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "subkey/test",
+		Data: map[string]interface{}{
+			"usage": "sign",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), reqSubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subkeyFingerprint := resp.Data["fingerprint"].(string)
+
+	reqSign := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Data: map[string]interface{}{
+			"input":              "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"algorithm":          "sha2-512",
+			"subkey_fingerprint": subkeyFingerprint,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := resp.Data["signature"].(string)
+
+	verify := func(name string) map[string]interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "verify/" + name,
+			Data: map[string]interface{}{
+				"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+				"signature": signature,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+
+	data := verify("test")
+	if !data["valid"].(bool) {
+		t.Fatal("expected the signature to be valid")
+	}
+	if data["signer_fingerprint"] != subkeyFingerprint {
+		t.Fatalf("expected the signer to be the subkey %s, got %s", subkeyFingerprint, data["signer_fingerprint"])
+	}
+	if data["hash_algorithm"] != "sha2-512" {
+		t.Fatalf("expected hash algorithm sha2-512, got %s", data["hash_algorithm"])
+	}
+	creationTime, err := time.Parse(time.RFC3339, data["creation_time"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(creationTime) > time.Minute {
+		t.Fatalf("unexpected signature creation time %s", creationTime)
+	}
+
+	data = verify("other")
+	if data["valid"].(bool) {
+		t.Fatal("expected the signature to be invalid with another key")
+	}
+	if _, ok := data["signer_fingerprint"]; ok {
+		t.Fatal("no signer expected for an invalid signature")
+	}
+	if data["issuer_key_id"] != subkeyFingerprint[24:] {
+		t.Fatalf("expected issuer key ID %s, got %s", subkeyFingerprint[24:], data["issuer_key_id"])
+	}
+}
+
+func TestGPG_SignWithSigningTime(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingTime := resp.Data["creation_time"].(string)
+
+	signWithTime := func(signingTime string) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/test",
+			Data: map[string]interface{}{
+				"input":        "dGhlIHF1aWNrIGJyb3duIGZveA==",
+				"signing_time": signingTime,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	if resp := signWithTime("yesterday"); !resp.IsError() {
+		t.Fatal("expected to fail, the signing time is not RFC3339")
+	}
+	if resp := signWithTime("2000-01-01T00:00:00Z"); !resp.IsError() {
+		t.Fatal("expected to fail, the key did not exist at the signing time")
+	}
+
+	first := signWithTime(signingTime)
+	if first.IsError() {
+		t.Fatalf("not expected error response: %#v", *first)
+	}
+	second := signWithTime(signingTime)
+	if first.Data["signature"] != second.Data["signature"] {
+		t.Fatal("expected identical signatures for the same input, key and signing time")
+	}
+
+	reqVerify := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "verify/test",
+		Data: map[string]interface{}{
+			"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"signature": first.Data["signature"],
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqVerify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data["valid"].(bool) {
+		t.Fatal("expected the signature to be valid")
+	}
+	if resp.Data["creation_time"] != signingTime {
+		t.Fatalf("expected the signature creation time %s, got %s", signingTime, resp.Data["creation_time"])
+	}
+}
+
+func TestGPG_SignWithNotations(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	handle("keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	sign := func(notations map[string]interface{}, critical string) *logical.Response {
+		return handle("sign/test", map[string]interface{}{
+			"input":              "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"notations":          notations,
+			"critical_notations": critical,
+		})
+	}
+
+	if resp := sign(map[string]interface{}{"reference": "build-123"}, ""); !resp.IsError() {
+		t.Fatal("expected to fail, the notation name has no domain")
+	}
+	if resp := sign(map[string]interface{}{"reference@example.com": "build-123"}, "build@example.com"); !resp.IsError() {
+		t.Fatal("expected to fail, the critical notation is not in the notations")
+	}
+
+	notations := map[string]interface{}{"reference@example.com": "build-123", "pipeline@example.com": "release"}
+	resp := sign(notations, "pipeline@example.com")
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	signature := resp.Data["signature"].(string)
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := packet.Read(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	critical := make(map[string]bool)
+	for _, notation := range p.(*packet.Signature).Notations {
+		critical[notation.Name] = notation.IsCritical
+	}
+	if !critical["pipeline@example.com"] || critical["reference@example.com"] {
+		t.Fatalf("expected only pipeline@example.com to be critical, got %#v", critical)
+	}
+
+	verify := func(knownNotations string) map[string]interface{} {
+		return handle("verify/test", map[string]interface{}{
+			"input":           "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"signature":       signature,
+			"known_notations": knownNotations,
+		}).Data
+	}
+	if data := verify(""); data["valid"].(bool) {
+		t.Fatal("expected the signature to be invalid, its critical notation is unknown")
+	}
+	data := verify("pipeline@example.com")
+	if !data["valid"].(bool) {
+		t.Fatal("expected the signature to be valid")
+	}
+	expected := map[string]string{"reference@example.com": "build-123", "pipeline@example.com": "release"}
+	if !reflect.DeepEqual(data["notations"], expected) {
+		t.Fatalf("expected the notations %#v, got %#v", expected, data["notations"])
+	}
+}
+
+func TestGPG_ExpiredKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	handle("config", map[string]interface{}{
+		"allow_seeded_keys": true,
+	})
+	// Seeded keys are created at the Unix epoch so they expire right away.
+	handle("keys/test", map[string]interface{}{
+		"real_name":   "Vault GPG test",
+		"algorithm":   "eddsa",
+		"seed":        "000102030405060708090a0b0c0d0e0f",
+		"key_expires": "24h",
+	})
+
+	operations := map[string]map[string]interface{}{
+		"sign/test": {
+			"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+		"sign-digest/test": {
+			"digest": "9ecb36561341d18eb65484e833efea61edc74b84cf5e6ae1b81c63533e25fc8f",
+		},
+		"encrypt/test": {
+			"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+	}
+	for path, data := range operations {
+		resp := handle(path, data)
+		if !resp.IsError() {
+			t.Fatalf("expected %s to fail, the key expired", path)
+		}
+		if expected := "key expired on 1970-01-02T00:00:00Z"; resp.Data["error"] != expected {
+			t.Fatalf("expected error %q for %s, got %q", expected, path, resp.Data["error"])
+		}
+
+		data["allow_expired"] = true
+		if resp = handle(path, data); resp.IsError() {
+			t.Fatalf("not expected error response for %s: %#v", path, *resp)
+		}
+	}
+
+	// The signatures of the expired key are only valid when the caller accepts them
+	signature := handle("sign/test", map[string]interface{}{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "allow_expired": true}).Data["signature"]
+	for _, path := range []string{"verify/test", "verify"} {
+		data := map[string]interface{}{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "signature": signature, "names": "test"}
+		if path == "verify/test" {
+			delete(data, "names")
+		}
+		if resp := handle(path, data); resp.IsError() || resp.Data["valid"] != false {
+			t.Fatalf("expected the signature verified with %s to not be valid, got %#v", path, resp)
+		}
+		data["allow_expired"] = true
+		if resp := handle(path, data); resp.IsError() || resp.Data["valid"] != true {
+			t.Fatalf("expected the signature verified with %s to be valid, got %#v", path, resp)
+		}
+	}
+}
+
+func TestGPG_VerifyCandidateKeys(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	teams := map[string]string{"first": "payments", "second": "payments", "third": "billing"}
+	for name, team := range teams {
+		handle("keys/"+name, map[string]interface{}{
+			"real_name": "Vault GPG test",
+			"algorithm": "eddsa",
+			"tags":      map[string]interface{}{"team": team},
+		})
+	}
+	handle("keys/untagged", map[string]interface{}{"real_name": "Vault GPG test", "algorithm": "eddsa"})
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	signature := handle("sign/second", map[string]interface{}{"input": input}).Data["signature"]
+	handle("rotate/second", nil)
+
+	if resp := handle("verify", map[string]interface{}{"input": input, "signature": signature}); !resp.IsError() {
+		t.Fatal("expected to fail, no candidate keys are selected")
+	}
+	if resp := handle("verify", map[string]interface{}{"names": "first", "tag": "team", "input": input, "signature": signature}); !resp.IsError() {
+		t.Fatal("expected to fail, names and tag cannot be used together")
+	}
+	if resp := handle("verify", map[string]interface{}{"names": "first,notfound", "input": input, "signature": signature}); !resp.IsError() {
+		t.Fatal("expected to fail, a candidate key does not exist")
+	}
+
+	selectors := map[string]map[string]interface{}{
+		"names": {"names": "first,second"},
+		"tag":   {"tag": "team:payments"},
+	}
+	for selector, data := range selectors {
+		data["input"] = input
+		data["signature"] = signature
+		resp := handle("verify", data)
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		if resp.Data["valid"] != true || resp.Data["key_name"] != "second" {
+			t.Fatalf("expected the previous version of the second key to be found with %s, got %#v", selector, resp.Data)
+		}
+	}
+
+	resp := handle("verify", map[string]interface{}{"names": []string{"first", "third"}, "input": input, "signature": signature})
+	if resp.Data["valid"] != false {
+		t.Fatal("expected the signature to not be valid for the candidate keys")
+	}
+	if _, ok := resp.Data["key_name"]; ok {
+		t.Fatalf("expected no key name when no candidate key made the signature, got %#v", resp.Data)
+	}
+}
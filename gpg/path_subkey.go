@@ -0,0 +1,235 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathSubkey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "subkey/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"usage": {
+				Type:        framework.TypeString,
+				Default:     "encrypt",
+				Description: `The usage of the generated subkey. Can be "encrypt" or "sign". Defaults to "encrypt".`,
+			},
+			"algorithm": {
+				Type:        framework.TypeString,
+				Description: `The public key algorithm of the generated subkey. Can be "rsa", "ecdsa" or "eddsa". Defaults to the algorithm of the primary key.`,
+			},
+			"key_bits": {
+				Type:        framework.TypeInt,
+				Description: "The number of bits to use. Defaults to the default_rsa_bits configuration. Only used if algorithm is rsa, or if it is not provided and the primary key is an RSA key.",
+			},
+			"key_expires": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The validity period of the generated subkey, either as a duration string or as a number of seconds. A zero value means the subkey never expires.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathSubkeyWrite,
+			},
+		},
+		HelpSynopsis:    pathSubkeyHelpSyn,
+		HelpDescription: pathSubkeyHelpDesc,
+	}
+}
+
+func pathAddEncryptionSubkey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + keyNameRegex("name") + "/add-encryption-subkey",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"algorithm": {
+				Type:        framework.TypeString,
+				Description: `The public key algorithm of the generated subkey. Can be "rsa", "ecdsa" or "eddsa". Defaults to the algorithm of the primary key.`,
+			},
+			"key_bits": {
+				Type:        framework.TypeInt,
+				Description: "The number of bits to use. Defaults to the default_rsa_bits configuration. Only used if algorithm is rsa, or if it is not provided and the primary key is an RSA key.",
+			},
+			"curve": {
+				Type:        framework.TypeString,
+				Description: `The elliptic curve of the generated subkey. Can be "curve25519", "curve448", "nistp256", "nistp384" or "nistp521", "p256", "p384" and "p521" are accepted as aliases. Defaults to the curve matching the algorithm. Cannot be used with rsa.`,
+			},
+			"key_expires": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The validity period of the generated subkey, either as a duration string or as a number of seconds. A zero value means the subkey never expires.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase of the key. Only required if the key is protected by a passphrase.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathAddEncryptionSubkeyWrite,
+			},
+		},
+		HelpSynopsis:    pathAddEncryptionSubkeyHelpSyn,
+		HelpDescription: pathAddEncryptionSubkeyHelpDesc,
+	}
+}
+
+// subkeyCurves are the curves an encryption subkey can be generated on in addition to the NIST curves, by name.
+var subkeyCurves = map[string]packet.Curve{
+	"curve25519": packet.Curve25519,
+	"curve448":   packet.Curve448,
+}
+
+func (b *backend) pathSubkeyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	usage := data.Get("usage").(string)
+	switch usage {
+	case "encrypt", "sign":
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported usage %s; must be \"encrypt\" or \"sign\"", usage)), logical.ErrInvalidRequest
+	}
+	return b.addSubkey(ctx, req, data, usage, "")
+}
+
+func (b *backend) pathAddEncryptionSubkeyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.addSubkey(ctx, req, data, "encrypt", data.Get("curve").(string))
+}
+
+// addSubkey generates a subkey with the given usage, on the named curve if it is not empty, and binds it to the
+// named key.
+func (b *backend) addSubkey(ctx context.Context, req *logical.Request, data *framework.FieldData, usage string, curve string) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	algorithm := data.Get("algorithm").(string)
+	passphrase := data.Get("passphrase").(string)
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(name)
+	}
+	if err = entry.checkKeyMaterialUpdatable(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+	if entity.PrivateKey == nil {
+		return logical.ErrorResponse("subkeys cannot be added to keys without a private key"), logical.ErrInvalidRequest
+	}
+	encrypted := entity.PrivateKey.Encrypted
+	if err = decryptPrivateKeys(entity, passphrase); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	policy, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	keyBits := policy.rsaBits(data)
+	var config *packet.Config
+	if algorithm == "" {
+		config, err = rotationConfig(entity)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err = setKeyLifetime(config, data.Get("key_expires").(int)); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		// The subkey uses the algorithm of the primary key, with the requested or default size for RSA
+		algorithm = publicKeyAlgorithmName(entity.PrimaryKey.PubKeyAlgo)
+		if config.Algorithm == packet.PubKeyAlgoRSA {
+			config.RSABits = keyBits
+		}
+	} else {
+		config, err = keyConfig(algorithm, "", keyBits, data.Get("key_expires").(int))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+	if err = policy.check(algorithm, keyBits); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if curve != "" {
+		subkeyCurve, ok := subkeyCurves[curve]
+		if !ok {
+			subkeyCurve, ok = nistCurves[curve]
+		}
+		if !ok {
+			return logical.ErrorResponse(fmt.Sprintf("unsupported curve %s; must be \"curve25519\", \"curve448\", %s", curve, nistCurveNames)), logical.ErrInvalidRequest
+		}
+		if config.Algorithm == packet.PubKeyAlgoRSA {
+			return logical.ErrorResponse("a curve cannot be used with an RSA subkey"), logical.ErrInvalidRequest
+		}
+		config.Curve = subkeyCurve
+	}
+
+	if usage == "sign" {
+		err = entity.AddSigningSubkey(config)
+	} else {
+		err = entity.AddEncryptionSubkey(config)
+	}
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	subkey := entity.Subkeys[len(entity.Subkeys)-1]
+
+	if encrypted {
+		if err = entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if err = serializePrivateWithoutSigning(&buf, entity); err != nil {
+		return nil, err
+	}
+
+	entry.SerializedKey = buf.Bytes()
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"fingerprint": hex.EncodeToString(subkey.PublicKey.Fingerprint[:]),
+		},
+	}, nil
+}
+
+const pathSubkeyHelpSyn = "Add a subkey to a named GPG key"
+
+const pathSubkeyHelpDesc = `
+This path generates a new encryption or signing subkey and binds it to the
+primary key of the named GPG key. The primary key and its self-signature are
+left untouched.
+`
+
+const pathAddEncryptionSubkeyHelpSyn = "Add an encryption subkey to a named GPG key"
+
+const pathAddEncryptionSubkeyHelpDesc = `
+This path generates a new encryption subkey and binds it to the primary key
+of the named GPG key, for example to rotate the encryption key while keeping
+the signing key. Messages are encrypted to the newest valid encryption subkey,
+the previous ones are kept to decrypt the messages encrypted to them.
+`
@@ -0,0 +1,394 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGPG_Subkey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"real_name":  "Vault GPG test",
+			"algorithm":  "eddsa",
+			"passphrase": "passphrase",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readKey := func() map[string]interface{} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/test",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+	before := readKey()
+
+	reqSubkey := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "subkey/test",
+		Data: map[string]interface{}{
+			"usage":       "sign",
+			"key_expires": "24h",
+			"passphrase":  "passphrase",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), reqSubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	fingerprint := resp.Data["fingerprint"].(string)
+
+	after := readKey()
+	if after["fingerprint"] != before["fingerprint"] {
+		t.Fatal("the primary key must not change")
+	}
+	subkeys := after["subkeys"].([]map[string]interface{})
+	if len(subkeys) != 2 {
+		t.Fatalf("expected 2 subkeys, got %d", len(subkeys))
+	}
+	if !reflect.DeepEqual(subkeys[0]["capabilities"], []string{"encrypt"}) {
+		t.Fatalf("expected the original subkey to encrypt, got %#v", subkeys[0]["capabilities"])
+	}
+	if subkeys[1]["fingerprint"] != fingerprint || !reflect.DeepEqual(subkeys[1]["capabilities"], []string{"sign"}) {
+		t.Fatalf("unexpected new subkey %#v", subkeys[1])
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(after["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := el[0]
+	for name, identity := range entity.Identities {
+		if err = entity.PrimaryKey.VerifyUserIdSignature(name, entity.PrimaryKey, identity.SelfSignature); err != nil {
+			t.Fatalf("self-signature is not valid anymore: %s", err)
+		}
+	}
+	subkey := entity.Subkeys[1]
+	if err = entity.PrimaryKey.VerifyKeySignature(subkey.PublicKey, subkey.Sig); err != nil {
+		t.Fatalf("subkey binding signature is not valid: %s", err)
+	}
+	if subkey.Sig.KeyLifetimeSecs == nil || *subkey.Sig.KeyLifetimeSecs != 24*60*60 {
+		t.Fatal("the subkey must expire in 24 hours")
+	}
+
+	reqSign := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Data: map[string]interface{}{
+			"input":      "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"passphrase": "passphrase",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqVerify := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "verify/test",
+		Data: map[string]interface{}{
+			"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"signature": resp.Data["signature"],
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), reqVerify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data["valid"].(bool) {
+		t.Fatal("signature made with the new subkey must be valid")
+	}
+
+	subkeyMustFail := func(keyName, usage, passphrase string) {
+		reqSubkey := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "subkey/" + keyName,
+			Data: map[string]interface{}{
+				"usage":      usage,
+				"passphrase": passphrase,
+			},
+		}
+		resp, _ := b.HandleRequest(context.Background(), reqSubkey)
+		if !resp.IsError() {
+			t.Fatalf("expected to fail, keyname: %s, usage: %s", keyName, usage)
+		}
+	}
+
+	subkeyMustFail("doNotExist", "encrypt", "passphrase")
+	subkeyMustFail("test", "unknown", "passphrase")
+	subkeyMustFail("test", "encrypt", "")
+	subkeyMustFail("test", "encrypt", "wrong")
+}
+
+func TestGPG_AddEncryptionSubkey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Seeded keys are created at the Unix epoch so the new subkey is always the most recent one.
+	handle(logical.UpdateOperation, "config", map[string]interface{}{"allow_seeded_keys": true})
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+		"seed":      "000102030405060708090a0b0c0d0e0f",
+	})
+	plaintext := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	encrypt := func() (string, string) {
+		resp := handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{"plaintext": plaintext})
+		if resp.IsError() {
+			t.Fatalf("not expected error response: %#v", *resp)
+		}
+		ciphertext := resp.Data["ciphertext"].(string)
+		resp = handle(logical.UpdateOperation, "inspect", map[string]interface{}{"ciphertext": ciphertext, "format": "ascii-armor"})
+		return ciphertext, resp.Data["recipient_key_ids"].([]string)[0]
+	}
+	oldCiphertext, oldRecipient := encrypt()
+	if subkey := handle(logical.ReadOperation, "keys/test", nil).Data["encryption_subkey_fingerprint"].(string); !strings.HasSuffix(subkey, oldRecipient) {
+		t.Fatalf("expected the encryption subkey to be %s, got %s", oldRecipient, subkey)
+	}
+
+	if resp := handle(logical.UpdateOperation, "keys/test/add-encryption-subkey", map[string]interface{}{"curve": "unknown"}); !resp.IsError() || !strings.Contains(resp.Data["error"].(string), `"nistp256"`) {
+		t.Fatalf("expected to fail with the supported curves, the curve is not supported: %#v", resp)
+	}
+	if resp := handle(logical.UpdateOperation, "keys/test/add-encryption-subkey", map[string]interface{}{"algorithm": "rsa", "curve": "nistp256"}); !resp.IsError() {
+		t.Fatal("expected to fail, a curve cannot be used with rsa")
+	}
+	resp := handle(logical.UpdateOperation, "keys/test/add-encryption-subkey", map[string]interface{}{
+		"curve":       "nistp384",
+		"key_expires": "2160h",
+	})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	fingerprint := resp.Data["fingerprint"].(string)
+
+	key := handle(logical.ReadOperation, "keys/test", nil).Data
+	subkeys := key["subkeys"].([]map[string]interface{})
+	if len(subkeys) != 2 {
+		t.Fatalf("expected 2 subkeys, got %d", len(subkeys))
+	}
+	if subkeys[1]["fingerprint"] != fingerprint || !reflect.DeepEqual(subkeys[1]["capabilities"], []string{"encrypt"}) {
+		t.Fatalf("unexpected new subkey %#v", subkeys[1])
+	}
+	if key["encryption_subkey_fingerprint"] != fingerprint {
+		t.Fatalf("expected the most recent encryption subkey %s, got %#v", fingerprint, key["encryption_subkey_fingerprint"])
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curve, _ := el[0].Subkeys[1].PublicKey.Curve(); curve != packet.CurveNistP384 {
+		t.Fatalf("expected the new subkey to use the P384 curve, got %s", curve)
+	}
+
+	newCiphertext, newRecipient := encrypt()
+	if newRecipient == oldRecipient || !strings.HasSuffix(fingerprint, newRecipient) {
+		t.Fatalf("expected the message to be encrypted to the new subkey %s, got %s", fingerprint, newRecipient)
+	}
+	for _, ciphertext := range []string{oldCiphertext, newCiphertext} {
+		resp = handle(logical.UpdateOperation, "decrypt/test", map[string]interface{}{"ciphertext": ciphertext, "format": "ascii-armor"})
+		if resp.IsError() || resp.Data["plaintext"] != plaintext {
+			t.Fatalf("expected the message to be decrypted, got %#v", resp)
+		}
+	}
+
+	// The curve names without the nist prefix are accepted as aliases
+	if resp = handle(logical.UpdateOperation, "keys/test/add-encryption-subkey", map[string]interface{}{"curve": "p521"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	el, err = openpgp.ReadArmoredKeyRing(strings.NewReader(handle(logical.ReadOperation, "keys/test", nil).Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curve, _ := el[0].Subkeys[2].PublicKey.Curve(); curve != packet.CurveNistP521 {
+		t.Fatalf("expected the new subkey to use the P521 curve, got %s", curve)
+	}
+}
+
+func TestGPG_SubkeyPublicOnlyKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	handle(logical.UpdateOperation, "keys/private", map[string]interface{}{"generate": false, "key": gpgKey})
+	handle(logical.UpdateOperation, "keys/public", map[string]interface{}{
+		"generate":          false,
+		"key":               handle(logical.ReadOperation, "keys/private", nil).Data["public_key"],
+		"allow_public_only": true,
+	})
+	for _, path := range []string{"subkey/public", "keys/public/add-encryption-subkey"} {
+		if resp := handle(logical.UpdateOperation, path, nil); !resp.IsError() {
+			t.Fatalf("expected %s to fail, the key does not have a private key", path)
+		}
+	}
+}
+
+func TestGPG_SubkeyDefaultAlgorithmPolicy(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	handle("keys/test", map[string]interface{}{"real_name": "Vault GPG test"})
+	for _, path := range []string{"subkey/test", "keys/test/add-encryption-subkey"} {
+		if resp := handle(path, map[string]interface{}{"key_bits": 1024}); !resp.IsError() || !strings.Contains(resp.Data["error"].(string), "min_rsa_bits") {
+			t.Fatalf("expected %s to fail, key_bits is smaller than min_rsa_bits: %#v", path, resp)
+		}
+	}
+
+	resp := handle("keys/test/add-encryption-subkey", map[string]interface{}{"key_bits": 3072})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/test",
+	}
+	read, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(read.Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bits, _ := el[0].Subkeys[len(el[0].Subkeys)-1].PublicKey.BitLength(); bits != 3072 {
+		t.Fatalf("expected the new subkey to have the requested 3072 bits, got %d", bits)
+	}
+}
+
+func TestGPG_SubkeyRevocationsKept(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA}
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := openpgp.NewEntity("Vault GPG signer", "", "", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.RevokeSubkey(&entity.Subkeys[0], packet.KeyCompromised, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.SignIdentity("Vault GPG test", signer, nil); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := handle(logical.UpdateOperation, "keys/test", map[string]interface{}{"generate": false, "key": buf.String()}); resp != nil && resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if resp := handle(logical.UpdateOperation, "keys/test/add-encryption-subkey", nil); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(handle(logical.ReadOperation, "keys/test", nil).Data["public_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(el[0].Subkeys) != 2 || !el[0].Subkeys[0].Revoked(time.Now()) {
+		t.Fatalf("expected the imported subkey to still be revoked, got %#v", el[0].Subkeys)
+	}
+	if el[0].Subkeys[1].Revoked(time.Now()) {
+		t.Fatal("expected the new subkey to not be revoked")
+	}
+	if signatures := el[0].Identities["Vault GPG test"].Signatures; len(signatures) != 2 {
+		t.Fatalf("expected the self-signature and the third-party certification, got %d signatures", len(signatures))
+	}
+}
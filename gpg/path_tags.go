@@ -0,0 +1,63 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathTags(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tags/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"tags": {
+				Type:        framework.TypeKVPairs,
+				Description: "The tags of the key, replacing the existing ones. Tag keys must not be empty or contain \":\".",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathTagsWrite,
+			},
+		},
+		HelpSynopsis:    pathTagsHelpSyn,
+		HelpDescription: pathTagsHelpDesc,
+	}
+}
+
+func (b *backend) pathTagsWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	tags := data.Get("tags").(map[string]string)
+	if err := validateTags(tags); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	entry, err := b.key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(name)
+	}
+
+	entry.Tags = tags
+	storageEntry, err := logical.StorageEntryJSON("key/"+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathTagsHelpSyn = "Update the tags of a named GPG key"
+
+const pathTagsHelpDesc = `
+This path replaces the tags of the named GPG key. Tags are arbitrary key-value
+pairs used to organize the keys, the keys can be listed by tag.
+`
@@ -0,0 +1,99 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"testing"
+)
+
+func TestGPG_Tags(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	createKey := func(name string, tags interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"real_name": "Vault GPG test",
+				"algorithm": "eddsa",
+				"tags":      tags,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	listKeys := func(tag string) []string {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.ListOperation,
+			Path:      "keys/",
+			Data: map[string]interface{}{
+				"tag": tag,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+
+	if resp := createKey("invalid", map[string]interface{}{"team:name": "payments"}); !resp.IsError() {
+		t.Fatal("expected to fail, tag keys must not contain \":\"")
+	}
+	createKey("payments", map[string]interface{}{"team": "payments", "env": "prod"})
+	createKey("billing", []interface{}{"team=billing", "env=prod"})
+	createKey("untagged", nil)
+
+	tests := map[string][]string{
+		"":              {"billing", "payments", "untagged"},
+		"team:payments": {"payments"},
+		"env:prod":      {"billing", "payments"},
+		"team":          {"billing", "payments"},
+		"team:unknown":  nil,
+	}
+	for tag, expected := range tests {
+		if keys := listKeys(tag); !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("expected keys %#v with tag %q, got %#v", expected, tag, keys)
+		}
+	}
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "tags/untagged",
+		Data: map[string]interface{}{
+			"tags": map[string]interface{}{"team": "payments"},
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Path = "tags/notfound"
+	if resp, _ := b.HandleRequest(context.Background(), req); !resp.IsError() {
+		t.Fatal("expected to fail, the key does not exist")
+	}
+	if keys := listKeys("team:payments"); !reflect.DeepEqual(keys, []string{"payments", "untagged"}) {
+		t.Fatalf("expected the updated key to be listed, got %#v", keys)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/untagged",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]string{"team": "payments"}; !reflect.DeepEqual(resp.Data["tags"], expected) {
+		t.Fatalf("expected tags %#v, got %#v", expected, resp.Data["tags"])
+	}
+}
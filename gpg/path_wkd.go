@@ -0,0 +1,86 @@
+package gpg
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base32"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"net/url"
+	"strings"
+)
+
+// zbase32 is the z-base-32 encoding used by the Web Key Directory.
+var zbase32 = base32.NewEncoding("ybndrfg8ejkmcpqxot1uwisza345h769").WithPadding(base32.NoPadding)
+
+func pathWKD(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "wkd/" + keyNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathWKDRead,
+			},
+		},
+		HelpSynopsis:    pathWKDHelpSyn,
+		HelpDescription: pathWKDHelpDesc,
+	}
+}
+
+func (b *backend) pathWKDRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.key(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return keyNotFound(data.Get("name").(string))
+	}
+	entity, err := b.entity(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	identities := make([]map[string]interface{}, 0, len(entity.Identities))
+	for _, id := range entityIdentities(entity) {
+		email := id.UserId.Email
+		at := strings.LastIndex(email, "@")
+		if at <= 0 || at == len(email)-1 {
+			continue
+		}
+		localPart := email[:at]
+		domain := strings.ToLower(email[at+1:])
+		hash := wkdHash(localPart)
+		query := "?l=" + url.QueryEscape(localPart)
+		identities = append(identities, map[string]interface{}{
+			"email":        email,
+			"hash":         hash,
+			"advanced_url": "https://openpgpkey." + domain + "/.well-known/openpgpkey/" + domain + "/hu/" + hash + query,
+			"direct_url":   "https://" + domain + "/.well-known/openpgpkey/hu/" + hash + query,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"identities": identities,
+		},
+	}, nil
+}
+
+// wkdHash returns the z-base-32 encoded SHA-1 digest of the lowercased local part of an email.
+func wkdHash(localPart string) string {
+	digest := sha1.Sum([]byte(strings.ToLower(localPart)))
+	return zbase32.EncodeToString(digest[:])
+}
+
+const pathWKDHelpSyn = "Compute the Web Key Directory hashes of a named GPG key"
+
+const pathWKDHelpDesc = `
+This path returns, for each identity of the named GPG key with an email, the
+Web Key Directory hash of the email local part and the URLs the key is looked
+up at with the advanced and direct methods.
+`
@@ -0,0 +1,55 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"reflect"
+	"testing"
+)
+
+func TestGPG_WKD(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Data: map[string]interface{}{
+			"algorithm": "eddsa",
+			"identities": []interface{}{
+				map[string]interface{}{"real_name": "Joe Doe", "email": "Joe.Doe@Example.ORG"},
+				map[string]interface{}{"real_name": "Joe Doe"},
+			},
+		},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "wkd/test",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]interface{}{
+		{
+			"email":        "Joe.Doe@Example.ORG",
+			"hash":         "iy9q119eutrkn8s1mk4r39qejnbu3n5q",
+			"advanced_url": "https://openpgpkey.example.org/.well-known/openpgpkey/example.org/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe",
+			"direct_url":   "https://example.org/.well-known/openpgpkey/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe",
+		},
+	}
+	if !reflect.DeepEqual(resp.Data["identities"], expected) {
+		t.Fatalf("expected identities %#v, got %#v", expected, resp.Data["identities"])
+	}
+
+	req.Path = "wkd/notfound"
+	if resp, _ = b.HandleRequest(context.Background(), req); !resp.IsError() {
+		t.Fatalf("expected to fail for a missing key, got %#v", resp)
+	}
+}
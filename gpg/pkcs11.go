@@ -0,0 +1,115 @@
+package gpg
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"io"
+)
+
+// keySourcePKCS11 is the key source of the keys whose private key is stored in a PKCS#11 token.
+const keySourcePKCS11 = "pkcs11"
+
+// PKCS11Provider gives access to the private keys stored in PKCS#11 tokens, for example in an HSM.
+// The private key material never leaves the token, the cryptographic operations are delegated to it.
+type PKCS11Provider interface {
+	// Signer returns the key with the given label in the token with the given label. The key must also implement
+	// crypto.Decrypter to decrypt messages with RSA keys.
+	Signer(tokenLabel string, keyLabel string) (crypto.Signer, error)
+}
+
+// FactoryWithPKCS11Provider gives a logical.Factory for the GPG plugin delegating the operations of the keys
+// stored in a PKCS#11 token to the provider.
+func FactoryWithPKCS11Provider(provider PKCS11Provider) logical.Factory {
+	return func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b := Backend()
+		b.pkcs11 = provider
+		if err := b.Setup(ctx, conf); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+}
+
+// pkcs11Signer returns the PKCS#11 key referenced by the labels, checking that it is the primary key of the entity.
+func (b *backend) pkcs11Signer(entity *openpgp.Entity, tokenLabel string, keyLabel string) (crypto.Signer, error) {
+	if b.pkcs11 == nil {
+		return nil, fmt.Errorf("no PKCS#11 provider is available to use keys stored in a PKCS#11 token")
+	}
+	signer, err := b.pkcs11.Signer(tokenLabel, keyLabel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the PKCS#11 key %s from the token %s: %s", keyLabel, tokenLabel, err)
+	}
+	if !samePublicKey(entity.PrimaryKey, signer.Public()) {
+		return nil, fmt.Errorf("the PKCS#11 key %s does not match the primary key", keyLabel)
+	}
+	return signer, nil
+}
+
+// attachPKCS11Key sets the private key of the primary key, and of the subkeys sharing its key material, of an entity
+// read from an entry stored with the pkcs11 key source. The token is only used when an operation needs the private key.
+func (b *backend) attachPKCS11Key(entry *keyEntry, entity *openpgp.Entity) {
+	if entry.KeySource != keySourcePKCS11 {
+		return
+	}
+	attach := func(pk *packet.PublicKey) *packet.PrivateKey {
+		return &packet.PrivateKey{
+			PublicKey: *pk,
+			PrivateKey: &pkcs11Key{
+				b:          b,
+				entity:     entity,
+				tokenLabel: entry.PKCS11TokenLabel,
+				keyLabel:   entry.PKCS11KeyLabel,
+				public:     pk.PublicKey,
+			},
+		}
+	}
+	entity.PrivateKey = attach(entity.PrimaryKey)
+	for i, subkey := range entity.Subkeys {
+		if samePublicKey(subkey.PublicKey, entity.PrimaryKey.PublicKey) {
+			entity.Subkeys[i].PrivateKey = attach(subkey.PublicKey)
+		}
+	}
+}
+
+// samePublicKey reports whether the OpenPGP public key has the given key material.
+func samePublicKey(pk *packet.PublicKey, public crypto.PublicKey) bool {
+	key, ok := pk.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(public)
+}
+
+// pkcs11Key is a crypto.Signer and a crypto.Decrypter delegating the operations to a key stored in a PKCS#11 token.
+type pkcs11Key struct {
+	b          *backend
+	entity     *openpgp.Entity
+	tokenLabel string
+	keyLabel   string
+	public     crypto.PublicKey
+}
+
+func (k *pkcs11Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+func (k *pkcs11Key) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	signer, err := k.b.pkcs11Signer(k.entity, k.tokenLabel, k.keyLabel)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(rand, digest, opts)
+}
+
+func (k *pkcs11Key) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	signer, err := k.b.pkcs11Signer(k.entity, k.tokenLabel, k.keyLabel)
+	if err != nil {
+		return nil, err
+	}
+	decrypter, ok := signer.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("the PKCS#11 key %s cannot decrypt", k.keyLabel)
+	}
+	return decrypter.Decrypt(rand, msg, opts)
+}
@@ -0,0 +1,124 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"testing"
+)
+
+// testPKCS11Provider stores the keys in memory by token and key labels.
+type testPKCS11Provider map[string]crypto.Signer
+
+func (p testPKCS11Provider) Signer(tokenLabel string, keyLabel string) (crypto.Signer, error) {
+	signer, ok := p[tokenLabel+"/"+keyLabel]
+	if !ok {
+		return nil, fmt.Errorf("key not found")
+	}
+	return signer, nil
+}
+
+func TestGPG_PKCS11Key(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+
+	entity, err := openpgp.NewEntity("Vault GPG test", "", "", &packet.Config{Algorithm: packet.PubKeyAlgoRSA, RSABits: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var publicKey bytes.Buffer
+	w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(data map[string]interface{}) *logical.Response {
+		request := map[string]interface{}{
+			"generate":           false,
+			"key":                publicKey.String(),
+			"key_source":         "pkcs11",
+			"pkcs11_token_label": "token",
+			"pkcs11_key_label":   "signing",
+		}
+		for field, value := range data {
+			request[field] = value
+		}
+		return handle(logical.UpdateOperation, "keys/test", request)
+	}
+
+	if resp := create(nil); !resp.IsError() {
+		t.Fatal("expected to fail, no PKCS#11 provider is available")
+	}
+	b.pkcs11 = testPKCS11Provider{
+		"token/signing": entity.PrivateKey.PrivateKey.(crypto.Signer),
+		"token/other":   entity.Subkeys[0].PrivateKey.PrivateKey.(crypto.Signer),
+	}
+	invalid := map[string]map[string]interface{}{
+		"an unsupported key source":  {"key_source": "tpm"},
+		"the key must be imported":   {"generate": true},
+		"the key cannot be exported": {"exportable": true},
+		"the key label is required":  {"pkcs11_key_label": ""},
+		"the key label is unknown":   {"pkcs11_key_label": "notfound"},
+		"the key does not match":     {"pkcs11_key_label": "other"},
+	}
+	for reason, data := range invalid {
+		if resp := create(data); !resp.IsError() {
+			t.Fatalf("expected to fail, %s", reason)
+		}
+	}
+	if resp := create(nil); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+
+	read := handle(logical.ReadOperation, "keys/test", nil)
+	if read.Data["key_source"] != "pkcs11" || read.Data["has_private_key"] != true {
+		t.Fatalf("expected a key stored in a PKCS#11 token, got %#v", read.Data)
+	}
+	entry, err := b.key(context.Background(), storage, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockType, err := keyBlockType(entry.SerializedKey); err != nil || blockType != openpgp.PublicKeyType {
+		t.Fatalf("expected the key stored in a PKCS#11 token to be armored as a public key, got %s, %s", blockType, err)
+	}
+
+	input := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	resp := handle(logical.UpdateOperation, "sign/test", map[string]interface{}{"input": input})
+	if resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	signature, err := base64.StdEncoding.DecodeString(resp.Data["signature"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = openpgp.CheckDetachedSignature(openpgp.EntityList{entity}, bytes.NewReader([]byte("the quick brown fox")), bytes.NewReader(signature), nil); err != nil {
+		t.Fatalf("expected the signature to be made with the PKCS#11 key: %s", err)
+	}
+
+	if resp = handle(logical.UpdateOperation, "rotate/test", nil); !resp.IsError() {
+		t.Fatal("expected to fail, the key material of a PKCS#11 key cannot be modified")
+	}
+}
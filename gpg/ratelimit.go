@@ -0,0 +1,58 @@
+package gpg
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keyRateLimiter limits the number of operations made with each key using a token bucket per key.
+// The buckets are kept in memory so the limit applies per Vault node.
+type keyRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newKeyRateLimiter() *keyRateLimiter {
+	return &keyRateLimiter{buckets: map[string]*tokenBucket{}}
+}
+
+// allow reports whether cost operations can be made at the given time with the named key limited to rate
+// operations per second, and consumes cost tokens if so.
+func (l *keyRateLimiter) allow(name string, rate int, cost int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[name]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(rate), last: now}
+		l.buckets[name] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() * float64(rate)
+	if bucket.tokens > float64(rate) {
+		bucket.tokens = float64(rate)
+	}
+	bucket.last = now
+	if bucket.tokens < float64(cost) {
+		return false
+	}
+	bucket.tokens -= float64(cost)
+	return true
+}
+
+// checkRateLimit returns a 429 error response if making the given number of operations with the named key exceeds its
+// maximum number of operations per second. A batch request counts one operation per batch item.
+func (b *backend) checkRateLimit(name string, entry *keyEntry, operations int) (*logical.Response, error) {
+	if entry.MaxOperationsPerSecond <= 0 || b.rateLimiter.allow(name, entry.MaxOperationsPerSecond, max(1, operations), time.Now()) {
+		return nil, nil
+	}
+	message := fmt.Sprintf("rate limit exceeded for key %s; at most %d operations per second are allowed", name, entry.MaxOperationsPerSecond)
+	return logical.ErrorResponse(message), logical.CodedError(http.StatusTooManyRequests, message)
+}
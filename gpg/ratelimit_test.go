@@ -0,0 +1,38 @@
+package gpg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyRateLimiter(t *testing.T) {
+	limiter := newKeyRateLimiter()
+	now := time.Unix(0, 0)
+
+	for i := 0; i < 2; i++ {
+		if !limiter.allow("test", 2, 1, now) {
+			t.Fatalf("expected operation %d to be allowed", i)
+		}
+	}
+	if limiter.allow("test", 2, 1, now) {
+		t.Fatal("expected to be limited, the key made 2 operations in the same second")
+	}
+	if !limiter.allow("other", 2, 1, now) {
+		t.Fatal("expected other keys to not be limited")
+	}
+	if !limiter.allow("test", 2, 1, now.Add(500*time.Millisecond)) {
+		t.Fatal("expected a token to be available again after half a second")
+	}
+	if limiter.allow("test", 2, 1, now.Add(500*time.Millisecond)) {
+		t.Fatal("expected to be limited, the available token has been used")
+	}
+	if !limiter.allow("batch", 2, 2, now) {
+		t.Fatal("expected a batch of 2 operations to be allowed")
+	}
+	if limiter.allow("batch", 2, 1, now) {
+		t.Fatal("expected to be limited, the batch used all the tokens")
+	}
+	if limiter.allow("larger", 2, 3, now) {
+		t.Fatal("expected a batch larger than the rate to be limited")
+	}
+}
@@ -0,0 +1,78 @@
+package gpg
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	openpgpecdsa "github.com/ProtonMail/go-crypto/openpgp/ecdsa"
+	openpgped25519 "github.com/ProtonMail/go-crypto/openpgp/ed25519"
+	"github.com/ProtonMail/go-crypto/openpgp/eddsa"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"golang.org/x/crypto/ssh"
+	"strings"
+	"time"
+)
+
+// sshPublicKey returns the OpenSSH public key of the authentication key of the entity, the most
+// recent valid authentication subkey or else the primary key, like gpg --export-ssh-key.
+func sshPublicKey(entity *openpgp.Entity) (string, error) {
+	now := time.Now()
+	var authenticationKey *packet.PublicKey
+	for i := len(entity.Subkeys) - 1; i >= 0; i-- {
+		subkey := entity.Subkeys[i]
+		if subkey.Sig.FlagsValid && subkey.Sig.FlagAuthenticate && !subkey.PublicKey.KeyExpired(subkey.Sig, now) && !subkey.Revoked(now) {
+			authenticationKey = subkey.PublicKey
+			break
+		}
+	}
+	if authenticationKey == nil {
+		if selfSignature, _ := entity.PrimarySelfSignature(); selfSignature != nil && selfSignature.FlagsValid && selfSignature.FlagAuthenticate {
+			authenticationKey = entity.PrimaryKey
+		}
+	}
+	if authenticationKey == nil {
+		return "", fmt.Errorf("the key does not have an authentication capable key or subkey")
+	}
+
+	var cryptoKey crypto.PublicKey
+	switch key := authenticationKey.PublicKey.(type) {
+	case *eddsa.PublicKey:
+		curve, err := authenticationKey.Curve()
+		if err != nil || curve != packet.Curve25519 {
+			return "", fmt.Errorf("the authentication key uses a curve not supported by SSH")
+		}
+		cryptoKey = ed25519.PublicKey(key.X)
+	case *openpgped25519.PublicKey:
+		cryptoKey = ed25519.PublicKey(key.Point)
+	case *openpgpecdsa.PublicKey:
+		curve, err := authenticationKey.Curve()
+		if err != nil {
+			return "", err
+		}
+		var ellipticCurve elliptic.Curve
+		switch curve {
+		case packet.CurveNistP256:
+			ellipticCurve = elliptic.P256()
+		case packet.CurveNistP384:
+			ellipticCurve = elliptic.P384()
+		case packet.CurveNistP521:
+			ellipticCurve = elliptic.P521()
+		default:
+			return "", fmt.Errorf("the authentication key uses a curve not supported by SSH")
+		}
+		cryptoKey = &ecdsa.PublicKey{Curve: ellipticCurve, X: key.X, Y: key.Y}
+	default:
+		// RSA keys are already standard library keys
+		cryptoKey = key
+	}
+
+	sshKey, err := ssh.NewPublicKey(cryptoKey)
+	if err != nil {
+		return "", fmt.Errorf("the authentication key cannot be converted to an SSH key: %s", err)
+	}
+	authorizedKey := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(sshKey)), "\n")
+	return fmt.Sprintf("%s openpgp:0x%s", authorizedKey, strings.ToUpper(authenticationKey.KeyIdShortString())), nil
+}
@@ -0,0 +1,156 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"sync"
+	"time"
+)
+
+// keyUsage is the number of operations made with a key and the time of the last one.
+type keyUsage struct {
+	Count    int64     `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// add merges the usage recorded since the last flush.
+func (u *keyUsage) add(other keyUsage) {
+	u.Count += other.Count
+	if other.LastUsed.After(u.LastUsed) {
+		u.LastUsed = other.LastUsed
+	}
+}
+
+// keyUsageTracker counts the operations made with each key in memory, the counts are only written to the storage
+// when they are flushed so the operations do not write the storage.
+type keyUsageTracker struct {
+	mu      sync.Mutex
+	pending map[string]keyUsage
+}
+
+func newKeyUsageTracker() *keyUsageTracker {
+	return &keyUsageTracker{pending: map[string]keyUsage{}}
+}
+
+// record counts an operation made with the named key at the given time.
+func (t *keyUsageTracker) record(name string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage := t.pending[name]
+	usage.add(keyUsage{Count: 1, LastUsed: now})
+	t.pending[name] = usage
+}
+
+// pendingUsage returns the usage of the named key not flushed yet.
+func (t *keyUsageTracker) pendingUsage(name string) keyUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.pending[name]
+}
+
+// forget drops the usage of the named key not flushed yet.
+func (t *keyUsageTracker) forget(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, name)
+}
+
+// flush adds the usage recorded since the last flush to the stored usage of the keys. The usage that cannot be
+// written is kept to be flushed again.
+func (t *keyUsageTracker) flush(ctx context.Context, s logical.Storage) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = map[string]keyUsage{}
+	t.mu.Unlock()
+
+	for name, usage := range pending {
+		if err := addStoredKeyUsage(ctx, s, name, usage); err != nil {
+			t.mu.Lock()
+			for name, usage := range pending {
+				restored := t.pending[name]
+				restored.add(usage)
+				t.pending[name] = restored
+			}
+			t.mu.Unlock()
+			return err
+		}
+		delete(pending, name)
+	}
+	return nil
+}
+
+// storedKeyUsage returns the usage of the named key written to the storage.
+func storedKeyUsage(ctx context.Context, s logical.Storage, name string) (keyUsage, error) {
+	var usage keyUsage
+	entry, err := s.Get(ctx, "usage/"+name)
+	if err != nil || entry == nil {
+		return usage, err
+	}
+	err = entry.DecodeJSON(&usage)
+	return usage, err
+}
+
+func addStoredKeyUsage(ctx context.Context, s logical.Storage, name string, usage keyUsage) error {
+	stored, err := storedKeyUsage(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	stored.add(usage)
+	entry, err := logical.StorageEntryJSON("usage/"+name, stored)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// usedKeyContextKey is the context key of the name of the stored key used by a tracked operation.
+type usedKeyContextKey struct{}
+
+// markKeyUsed records in the context of a tracked operation that it uses the named stored key.
+func markKeyUsed(ctx context.Context, name string) {
+	if used, ok := ctx.Value(usedKeyContextKey{}).(*string); ok {
+		*used = name
+	}
+}
+
+// withUsageTracking wraps an operation to count its successes with the stored key it marked as used when the
+// tracking of the usage of the keys is enabled. The operations not using a stored key, for example with a symmetric
+// passphrase, are not counted so no usage is written for names without a key.
+func (b *backend) withUsageTracking(callback framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		used := new(string)
+		resp, err := callback(context.WithValue(ctx, usedKeyContextKey{}, used), req, data)
+		if err != nil || resp.IsError() || *used == "" {
+			return resp, err
+		}
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if config.TrackKeyUsage {
+			b.usageTracker.record(*used, time.Now())
+		}
+		return resp, nil
+	}
+}
+
+// addKeyUsage adds the usage of the named key to its read response.
+func (b *backend) addKeyUsage(ctx context.Context, s logical.Storage, name string, resp *logical.Response) error {
+	usage, err := storedKeyUsage(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	usage.add(b.usageTracker.pendingUsage(name))
+
+	var lastUsed interface{}
+	if !usage.LastUsed.IsZero() {
+		lastUsed = usage.LastUsed.UTC().Format(time.RFC3339)
+	}
+	resp.Data["usage_count"] = usage.Count
+	resp.Data["last_used"] = lastUsed
+	return nil
+}
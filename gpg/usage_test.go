@@ -0,0 +1,76 @@
+package gpg
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/logical"
+	"testing"
+	"time"
+)
+
+func TestGPG_KeyUsage(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend()
+
+	handle := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+		}
+		resp, _ := b.HandleRequest(context.Background(), req)
+		return resp
+	}
+	usage := func() (interface{}, interface{}) {
+		resp := handle(logical.ReadOperation, "keys/test", nil)
+		return resp.Data["usage_count"], resp.Data["last_used"]
+	}
+
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	handle(logical.UpdateOperation, "sign/test", map[string]interface{}{"input": input})
+	if count, lastUsed := usage(); count != int64(0) || lastUsed != nil {
+		t.Fatalf("expected no usage when the tracking is disabled, got %v and %v", count, lastUsed)
+	}
+
+	handle(logical.UpdateOperation, "config", map[string]interface{}{"track_key_usage": true})
+	handle(logical.UpdateOperation, "sign/test", map[string]interface{}{"input": input})
+	handle(logical.UpdateOperation, "sign/test", map[string]interface{}{"input": "Not base64 encoded"})
+	ciphertext := handle(logical.UpdateOperation, "encrypt/test", map[string]interface{}{"plaintext": input, "format": "base64"}).Data["ciphertext"]
+	handle(logical.UpdateOperation, "decrypt/test", map[string]interface{}{"ciphertext": ciphertext})
+	handle(logical.UpdateOperation, "sign-digest/test", map[string]interface{}{"digest": "5ebe2294ecd0e0f08eab7690d2a6ee6926ae2d6a9bfd0cb7d8fc9d8d00a0ed24"})
+	count, lastUsed := usage()
+	if count != int64(4) {
+		t.Fatalf("expected 4 successful operations, got %v", count)
+	}
+	if used, err := time.Parse(time.RFC3339, lastUsed.(string)); err != nil || time.Since(used) > time.Minute {
+		t.Fatalf("unexpected last use %v", lastUsed)
+	}
+
+	// The operations without a stored key are not counted, no usage is written for their names.
+	if resp := handle(logical.UpdateOperation, "encrypt/notfound", map[string]interface{}{"plaintext": input, "symmetric_passphrase": "passphrase"}); resp.IsError() {
+		t.Fatalf("not expected error response: %#v", *resp)
+	}
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := storage.List(context.Background(), "usage/"); err != nil || len(keys) != 1 || keys[0] != "test" {
+		t.Fatalf("expected only the usage of the stored key, got %v", keys)
+	}
+	b = Backend()
+	if count, _ := usage(); count != int64(4) {
+		t.Fatalf("expected the usage to be stored, got %v", count)
+	}
+
+	handle(logical.DeleteOperation, "keys/test", nil)
+	handle(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"real_name": "Vault GPG test",
+		"algorithm": "eddsa",
+	})
+	if count, lastUsed := usage(); count != int64(0) || lastUsed != nil {
+		t.Fatalf("expected the usage to be deleted with the key, got %v and %v", count, lastUsed)
+	}
+}